@@ -0,0 +1,128 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// does not check that the array is indeed heap-ordered
+// k sift-ups cost up to k*log(n), re-heapifying the touched subtrees costs
+// about 2*k+log(n)*log(n), the cheaper one is used
+func PushAll(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap *[]uint64, elems []uint64) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	l := (len(*heap) / incr)
+	n := l + (len(elems) / incr)
+
+	*heap = append(*heap, elems...)
+	if n-l <= bits.Len(uint(n)) {
+		for j := l; j < n; j++ {
+			up(ts0, compar, *heap, j)
+		}
+		return
+	}
+	heapify(ts0, compar, *heap, l, n, n)
+}
+
+// merges the heapified src into the heap, src is left untouched
+// the larger of the two heaps keeps its place, the smaller one is pushed
+func Merge(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap *[]uint64, src []uint64) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	l := (len(*heap) / incr)
+	k := (len(src) / incr)
+	if k <= l {
+		PushAll(ts0, compar, heap, src)
+		return
+	}
+
+	// rotate the smaller heap behind the larger one
+	tmp := append([]uint64(nil), (*heap)...)
+	*heap = append((*heap)[:0], src...)
+	PushAll(ts0, compar, heap, tmp)
+}
+
+// deletes items from the heap at the given positions in one pass
+// the positions may be unsorted, duplicates are removed once
+func RemoveAll(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap *[]uint64, indices []int) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	n := (len(*heap) / incr)
+	idx := unique(indices)
+	if len(idx) == 0 {
+		return
+	}
+	if idx[0] < 0 || idx[len(idx)-1] >= n {
+		panic("RemoveAll: index out of range")
+	}
+	m := n - len(idx)
+
+	// fill the holes below m with the survivors from the top
+	t, last, holes := len(idx)-1, n-1, 0
+	for _, h := range idx {
+		if h >= m {
+			break
+		}
+		for t >= 0 && idx[t] == last {
+			t--
+			last--
+		}
+		copy((*heap)[h*incr:h*incr+incr], (*heap)[last*incr:last*incr+incr])
+		last--
+		holes++
+	}
+	(*heap) = (*heap)[:m*incr]
+
+	if holes*bits.Len(uint(m)) >= m/2 {
+		heapify(ts0, compar, *heap, 0, m, m)
+		return
+	}
+	// re-heapify the paths from the holes to the root, bottom up
+	var path []int
+	for _, h := range idx[:holes] {
+		for ; h > 0; h = (h - 1) / 2 {
+			path = append(path, h)
+		}
+	}
+	path = append(path, 0)
+	sort.Sort(sort.Reverse(sort.IntSlice(path)))
+	for i, j := range path {
+		if i == 0 || path[i-1] != j {
+			down(ts0, compar, *heap, j, m)
+		}
+	}
+}
+
+// re-establishes the heap ordering after the positions lo..hi-1 have changed
+func heapify(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap []uint64, lo, hi, n int) {
+	for {
+		for i := hi - 1; i >= lo; i-- {
+			down(ts0, compar, heap, i, n)
+		}
+		if lo == 0 {
+			break
+		}
+		lo, hi = (lo-1)/2, min(lo, hi/2)
+	}
+}
+
+func unique(indices []int) []int {
+	idx := make([]int, len(indices))
+	copy(idx, indices)
+	sort.Ints(idx)
+	k := 0
+	for i := range idx {
+		if i == 0 || idx[i] != idx[k-1] {
+			idx[k] = idx[i]
+			k++
+		}
+	}
+	return idx[:k]
+}
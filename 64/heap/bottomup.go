@@ -0,0 +1,65 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+// like Remove, but sifts down bottom-up, see downBottomUp
+func RemoveBottomUp(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap *[]uint64, i int) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	n := (len(*heap) / incr) - 1
+	if n != i {
+		copy((*heap)[i*incr:i*incr+incr], (*heap)[n*incr:n*incr+incr])
+		downBottomUp(ts0, compar, (*heap), i, n)
+		if i != 0 {
+			up(ts0, compar, (*heap), i)
+		}
+	}
+	(*heap) = (*heap)[:n*incr]
+}
+
+// like Heapify, but sifts down bottom-up, see downBottomUp
+func HeapifyBottomUp(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, dst []uint64, heap []uint64) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	n := (len(heap) / incr)
+	if &dst[0] == &heap[0] {
+		for i := n/2 - 1; i >= 0; i-- {
+			downBottomUp(ts0, compar, heap, i, n)
+		}
+	} else {
+		// FIXME: out of place heapify not implemented
+		panic("FIXME: out of place heapify not implemented")
+	}
+}
+
+// the bottom-up (Floyd) sift-down descends along the smaller children to a
+// leaf with one compare per level, then climbs back to the slot of the element
+// the element usually belongs near a leaf, so it takes about half the compares
+func downBottomUp(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap []uint64, i, n int) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	k := i
+	for {
+		j1 := 2*k + 1
+		if j1 >= n || j1 < 0 { // j1 < 0 after uint64 overflow
+			break
+		}
+		j := j1 // left child
+		if j2 := j1 + 1; j2 < n && compar(&heap[j1*incr], &heap[j2*incr]) >= 0 {
+			j = j2 // = 2*k + 2  // right child
+		}
+		k = j
+	}
+	for k != i && compar(&heap[i*incr], &heap[k*incr]) < 0 {
+		k = (k - 1) / 2
+	}
+	if k == i {
+		return
+	}
+	shiftdown(heap, incr, i, k)
+}
@@ -32,11 +32,7 @@ func Remove(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap *[]uint64,
 
 	n := (len(*heap) / incr) - 1
 	if n != i {
-		for q := 0; q < incr; q++ { // swap
-			x := (*heap)[i*incr+q]
-			(*heap)[i*incr+q] = (*heap)[n*incr+q]
-			(*heap)[n*incr+q] = x
-		}
+		swap(*heap, incr, i, n)
 		down(ts0, compar, (*heap), i, n)
 		if i != 0 {
 			up(ts0, compar, (*heap), i)
@@ -47,29 +43,36 @@ func Remove(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap *[]uint64,
 
 // another loads the second smallest value to heap[1]
 func Another(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap []uint64) {
+	Prefix(ts0, compar, heap, 2)
+}
+
+// prefix loads the k smallest values to heap[0..k-1] in the sorted order
+// the heap stays heap-ordered, the candidates for heap[i] are heap[i..2*i]
+func Prefix(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap []uint64, k int) {
 	incr := int((*ts0)[0])
 	_ = incr
 
-	// first we check that [1] < [2]
-
-	if (len(heap)/incr) <= 2 || compar(&heap[1], &heap[2]) <= 0 {
-		// ok
-		return
-	}
-	for q := 0; q < incr; q++ { // swap
-		x := heap[1*incr+q]
-		heap[1*incr+q] = heap[2*incr+q]
-		heap[2*incr+q] = x
+	n := (len(heap) / incr)
+	for i := 1; i < k && i < n; i++ {
+		c := i
+		for j := i + 1; j <= 2*i && j < n; j++ {
+			if compar(&heap[j*incr], &heap[c*incr]) < 0 {
+				c = j
+			}
+		}
+		if c == i {
+			continue
+		}
+		swap(heap, incr, i, c)
+		down(ts0, compar, heap, c, n)
 	}
-
-	down(ts0, compar, heap, 2, (len(heap) / incr)) //FIXME: shouldn't be len(heap)-1?
 }
 
 func Fix(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap []uint64, i int) {
 	incr := int((*ts0)[0])
 	_ = incr
 
-	down(ts0, compar, heap, i, (len(heap) / incr)) //FIXME: shouldn't be len(heap)-1?
+	down(ts0, compar, heap, i, (len(heap) / incr)) // n is a length, not the last index
 	up(ts0, compar, heap, i)
 }
 
@@ -88,45 +91,49 @@ func Heapify(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, dst []uint64, h
 	}
 }
 
+// the element stays in place while its slot is searched, then the parents are
+// shifted down into the hole and the element is written once, see shiftup
 func up(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap []uint64, j int) {
 	incr := int((*ts0)[0])
 	_ = incr
 
-	for {
-		i := (j - 1) / 2 // parent
-		if i == j || compar(&heap[j*incr], &heap[i*incr]) >= 0 {
+	k := j
+	for k > 0 {
+		i := (k - 1) / 2 // parent
+		if compar(&heap[j*incr], &heap[i*incr]) >= 0 {
 			break
 		}
-		for q := 0; q < incr; q++ { // swap
-			x := heap[i*incr+q]
-			heap[i*incr+q] = heap[j*incr+q]
-			heap[j*incr+q] = x
-		}
-		j = i
+		k = i
 	}
+	if k == j {
+		return
+	}
+	shiftup(heap, incr, k, j)
 }
 
+// the element stays in place while its slot is searched, then the children
+// are shifted up into the hole and the element is written once, see shiftdown
 func down(ts0 *[1]uintptr, compar func(*uint64, *uint64) int, heap []uint64, i, n int) {
 	incr := int((*ts0)[0])
 	_ = incr
 
+	k := i
 	for {
-		j1 := 2*i + 1
+		j1 := 2*k + 1
 		if j1 >= n || j1 < 0 { // j1 < 0 after uint64 overflow
 			break
 		}
 		j := j1 // left child
 		if j2 := j1 + 1; j2 < n && compar(&heap[j1*incr], &heap[j2*incr]) >= 0 {
-			j = j2 // = 2*i + 2  // right child
+			j = j2 // = 2*k + 2  // right child
 		}
 		if compar(&heap[j*incr], &heap[i*incr]) >= 0 {
 			break
 		}
-		for q := 0; q < incr; q++ { // swap
-			x := heap[i*incr+q]
-			heap[i*incr+q] = heap[j*incr+q]
-			heap[j*incr+q] = x
-		}
-		i = j
+		k = j
+	}
+	if k == i {
+		return
 	}
+	shiftdown(heap, incr, i, k)
 }
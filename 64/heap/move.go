@@ -0,0 +1,143 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/bits"
+)
+
+// the records are moved with copy through a scratch buffer on the stack,
+// chunk words at a time, records up to small words are moved word by word
+const (
+	chunk = 32
+	small = 4
+)
+
+// swaps the records at the slots i and j
+func swap(heap []uint64, incr, i, j int) {
+	if incr <= small {
+		for q := 0; q < incr; q++ {
+			x := heap[i*incr+q]
+			heap[i*incr+q] = heap[j*incr+q]
+			heap[j*incr+q] = x
+		}
+		return
+	}
+	var buf [chunk]uint64
+	for q := 0; q < incr; q += chunk {
+		w := min(chunk, incr-q)
+		a, b := heap[i*incr+q:i*incr+q+w], heap[j*incr+q:j*incr+q+w]
+		copy(buf[:w], a)
+		copy(a, b)
+		copy(b, buf[:w])
+	}
+}
+
+// moves the record at j up to its ancestor k, the path shifts down
+func shiftup(heap []uint64, incr, k, j int) {
+	if incr <= small {
+		for q := 0; q < incr; q++ {
+			x := heap[j*incr+q]
+			for p := j; p != k; {
+				i := (p - 1) / 2
+				heap[p*incr+q] = heap[i*incr+q]
+				p = i
+			}
+			heap[k*incr+q] = x
+		}
+		return
+	}
+	var buf [chunk]uint64
+	for q := 0; q < incr; q += chunk {
+		w := min(chunk, incr-q)
+		copy(buf[:w], heap[j*incr+q:])
+		for p := j; p != k; {
+			i := (p - 1) / 2
+			copy(heap[p*incr+q:p*incr+q+w], heap[i*incr+q:])
+			p = i
+		}
+		copy(heap[k*incr+q:k*incr+q+w], buf[:w])
+	}
+}
+
+// moves the record at i down to its descendant k, the path shifts up
+func shiftdown(heap []uint64, incr, i, k int) {
+	if incr <= small {
+		for q := 0; q < incr; q++ {
+			x := heap[k*incr+q]
+			for p := k; p != i; {
+				j := (p - 1) / 2
+				heap[j*incr+q], x = x, heap[j*incr+q]
+				p = j
+			}
+			heap[k*incr+q] = x
+		}
+		return
+	}
+	var buf [chunk]uint64
+	y := uint(k + 1)
+	for q := 0; q < incr; q += chunk {
+		w := min(chunk, incr-q)
+		copy(buf[:w], heap[i*incr+q:])
+		for p := i; p != k; {
+			c := int(y>>(bits.Len(y)-bits.Len(uint(p+1))-1)) - 1 // towards k
+			copy(heap[p*incr+q:p*incr+q+w], heap[c*incr+q:])
+			p = c
+		}
+		copy(heap[k*incr+q:k*incr+q+w], buf[:w])
+	}
+}
+
+// moves the record at the slot j up to its ancestor slot k in a paged heap
+func shiftupPaged(page int, heap []uint64, incr, k, j int) {
+	if incr <= small {
+		for q := 0; q < incr; q++ {
+			x := heap[j*incr+q]
+			for p := j; p != k; {
+				i := parent(page, p)
+				heap[p*incr+q] = heap[i*incr+q]
+				p = i
+			}
+			heap[k*incr+q] = x
+		}
+		return
+	}
+	var buf [chunk]uint64
+	for q := 0; q < incr; q += chunk {
+		w := min(chunk, incr-q)
+		copy(buf[:w], heap[j*incr+q:])
+		for p := j; p != k; {
+			i := parent(page, p)
+			copy(heap[p*incr+q:p*incr+q+w], heap[i*incr+q:])
+			p = i
+		}
+		copy(heap[k*incr+q:k*incr+q+w], buf[:w])
+	}
+}
+
+// moves the record at the slot path[0] down to the slot path[len-1] in a
+// paged heap, the path shifts up
+func shiftdownPaged(heap []uint64, incr int, path []int) {
+	i, k := path[0], path[len(path)-1]
+	if incr <= small {
+		for q := 0; q < incr; q++ {
+			z := heap[i*incr+q]
+			for t := 1; t < len(path); t++ {
+				heap[path[t-1]*incr+q] = heap[path[t]*incr+q]
+			}
+			heap[k*incr+q] = z
+		}
+		return
+	}
+	var buf [chunk]uint64
+	for q := 0; q < incr; q += chunk {
+		w := min(chunk, incr-q)
+		copy(buf[:w], heap[i*incr+q:])
+		for t := 1; t < len(path); t++ {
+			copy(heap[path[t-1]*incr+q:path[t-1]*incr+q+w], heap[path[t]*incr+q:])
+		}
+		copy(heap[k*incr+q:k*incr+q+w], buf[:w])
+	}
+}
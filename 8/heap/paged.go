@@ -0,0 +1,168 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+// the paged (B-heap) layout packs the subtrees into pages of page slots
+// the root page holds the top log2(page) levels at the slots 1..page-1
+// any other page holds a pair of sibling subtrees rooted at the slots 2 and 3
+// the pages are in the breadth-first order, a page has page/2 child pages
+// the elements fill the root page and then the other pages one by one, every
+// page in the breadth-first order, so the slots grow with the fill index and
+// only the last page is partly used, the tree is a little deeper than the
+// complete one
+// the n elements are addressed by Paged(page, i), the holes are not compared
+
+// returns the slot of the i-th element of a paged heap
+// i is an index in the fill order, page is a power of two, at least 4
+func Paged(page int, i int) int {
+	return slot(page, i+1)
+}
+
+// returns the number of the slots of a paged heap of n elements
+func PagedLen(page int, n int) int {
+	return size(page, n)
+}
+
+// returns the fill index of the parent of the i-th element, i > 0
+func PagedParent(page int, i int) int {
+	return order(page, parent(page, slot(page, i+1))) - 1
+}
+
+// pushes onto a paged heap of n elements, the heap grows to hold the slot
+func PushPaged(ts0 *[1]uintptr, page int, compar func(*uint8, *uint8) int, heap *[]uint8, n int, elem []uint8) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	j := slot(page, n+1)
+	if m := size(page, n+1) * incr; len(*heap) < m {
+		*heap = append(*heap, make([]uint8, m-len(*heap))...)
+	}
+	copy((*heap)[j*incr:j*incr+incr], elem)
+	upPaged(ts0, page, compar, *heap, j)
+}
+
+// deletes the i-th element of a paged heap of n elements, the heap shrinks
+func RemovePaged(ts0 *[1]uintptr, page int, compar func(*uint8, *uint8) int, heap *[]uint8, n, i int) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	j, last := slot(page, i+1), slot(page, n)
+	if j != last {
+		swap(*heap, incr, j, last)
+		downPaged(ts0, page, compar, *heap, j, n-1)
+		if j != 1 {
+			upPaged(ts0, page, compar, *heap, j)
+		}
+	}
+	*heap = (*heap)[:size(page, n-1)*incr]
+}
+
+// re-establishes the ordering after the i-th element of n has changed
+func FixPaged(ts0 *[1]uintptr, page int, compar func(*uint8, *uint8) int, heap []uint8, n, i int) {
+	j := slot(page, i+1)
+	downPaged(ts0, page, compar, heap, j, n)
+	upPaged(ts0, page, compar, heap, j)
+}
+
+// establishes the ordering of a paged heap of n elements
+func HeapifyPaged(ts0 *[1]uintptr, page int, compar func(*uint8, *uint8) int, heap []uint8, n int) {
+	for x := n; x > 0; x-- {
+		downPaged(ts0, page, compar, heap, slot(page, x), n)
+	}
+}
+
+// j and i are slots, the sifts shift the path into the hole like up and down
+func upPaged(ts0 *[1]uintptr, page int, compar func(*uint8, *uint8) int, heap []uint8, j int) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	k := j
+	for k != 1 {
+		i := parent(page, k)
+		if compar(&heap[j*incr], &heap[i*incr]) >= 0 {
+			break
+		}
+		k = i
+	}
+	if k == j {
+		return
+	}
+	shiftupPaged(page, heap, incr, k, j)
+}
+
+func downPaged(ts0 *[1]uintptr, page int, compar func(*uint8, *uint8) int, heap []uint8, i, n int) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	var path [2 * 64]int // the slots from i down, the tree is not that deep
+	path[0] = i
+	k, d := i, 1
+	for {
+		j1 := child(page, k)
+		x := order(page, j1)
+		if x > n {
+			break
+		}
+		j := j1 // left child
+		if x < n && compar(&heap[j1*incr], &heap[(j1+1)*incr]) >= 0 {
+			j = j1 + 1 // right child
+		}
+		if compar(&heap[j*incr], &heap[i*incr]) >= 0 {
+			break
+		}
+		k = j
+		path[d] = k
+		d++
+	}
+	if k == i {
+		return
+	}
+	shiftdownPaged(heap, incr, path[:d])
+}
+
+// the slot of the 1-based fill index x
+func slot(page, x int) int {
+	if x < page {
+		return x // the root page
+	}
+	x -= page
+	return (1+x/(page-2))*page + 2 + x%(page-2)
+}
+
+// the 1-based fill index of the slot j
+func order(page, j int) int {
+	if j < page {
+		return j // the root page
+	}
+	return page + (j/page-1)*(page-2) + j&(page-1) - 2
+}
+
+// the number of the slots of n elements, up to the last used slot
+func size(page, n int) int {
+	if n == 0 {
+		return 0
+	}
+	return slot(page, n) + 1
+}
+
+// the left child slot of the slot j, the right child is next
+func child(page, j int) int {
+	l := j & (page - 1)
+	if l < page/2 {
+		return j + l
+	}
+	p := (j-l)/2 + 1 + l - page/2 // the child page
+	return p*page + 2
+}
+
+// the parent slot of the slot j
+func parent(page, j int) int {
+	l := j & (page - 1)
+	if l > 3 || j < page {
+		return j - l + l/2
+	}
+	p := j/page - 1
+	return (p/(page/2))*page + page/2 + p%(page/2)
+}
@@ -0,0 +1,74 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"runtime"
+	"sync"
+)
+
+// like Heapify, but the subtrees below the top levels are heapified by the
+// workers concurrently, then the top levels are finished serially
+// the subtrees are disjoint, so no worker touches a record of another one
+func HeapifyParallel(ts0 *[1]uintptr, compar func(*uint8, *uint8) int, dst []uint8, heap []uint8, workers int) {
+	incr := int((*ts0)[0])
+	_ = incr
+
+	n := (len(heap) / incr)
+	if &dst[0] != &heap[0] {
+		// FIXME: out of place heapify not implemented
+		panic("FIXME: out of place heapify not implemented")
+	}
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// the 1<<l subtree roots at the level l, a few per worker to even out
+	// the partial last level
+	l := uint(0)
+	for 1<<l < 4*workers && 2<<l-1 < n/2 {
+		l++
+	}
+	top := n / 2 // the records left to the serial pass
+	if workers > 1 && l > 0 {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for r := 1<<l - 1 + w; r < 2<<l-1; r += workers {
+					subtree(ts0, compar, heap, r, n)
+				}
+			}(w)
+		}
+		wg.Wait()
+		top = 1<<l - 1
+	}
+	for i := top - 1; i >= 0; i-- {
+		down(ts0, compar, heap, i, n)
+	}
+}
+
+// heapifies the subtree at the root r level by level, the descendants of r
+// at the depth d are the 1<<d records from (r+1)<<d - 1
+func subtree(ts0 *[1]uintptr, compar func(*uint8, *uint8) int, heap []uint8, r, n int) {
+	d := uint(0)
+	for (r+1)<<(d+1)-1 < n/2 {
+		d++
+	}
+	for ; ; d-- {
+		lo := (r+1)<<d - 1
+		hi := lo + 1<<d
+		if hi > n/2 {
+			hi = n / 2
+		}
+		for i := hi - 1; i >= lo; i-- {
+			down(ts0, compar, heap, i, n)
+		}
+		if d == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a binomial heap (a mergeable priority queue).
+//
+// The heap is ordered using the same compare function as the slice heaps,
+// a func(*T, *T) int returning a negative number when the first element
+// goes first.
+package heap
+
+// Node is an element pushed onto the heap. It is a handle that stays valid
+// until the element is popped, also when the heap is melded into another.
+type Node[T any] struct {
+	Elem T
+
+	tree *tree[T]
+}
+
+type tree[T any] struct {
+	node    *Node[T]
+	parent  *tree[T]
+	child   *tree[T] // child of the highest degree
+	sibling *tree[T] // next root, or next child of a lower degree
+	degree  int
+}
+
+// Heap is a binomial heap. The zero value is not usable, use New.
+type Heap[T any] struct {
+	compar func(*T, *T) int
+	head   *tree[T] // roots by increasing degree
+	n      int
+}
+
+// New returns an empty heap.
+// The compar is a compare function.
+func New[T any](compar func(*T, *T) int) *Heap[T] {
+	return &Heap[T]{compar: compar}
+}
+
+// Len returns the number of elements in the heap.
+func (h *Heap[T]) Len() int {
+	return h.n
+}
+
+// Top returns the top element without removing it, nil if the heap is empty.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap[T]) Top() *Node[T] {
+	_, t := h.top()
+	if t == nil {
+		return nil
+	}
+	return t.node
+}
+
+// Push pushes the element onto the heap and returns its handle.
+// The complexity is O(log(n)), O(1) amortized.
+func (h *Heap[T]) Push(elem *T) *Node[T] {
+	x := &Node[T]{Elem: *elem}
+	x.tree = &tree[T]{node: x}
+	h.head = h.union(h.head, x.tree)
+	h.n++
+	return x
+}
+
+// Pop removes the top element and returns it, nil if the heap is empty.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap[T]) Pop() *Node[T] {
+	prev, t := h.top()
+	if t == nil {
+		return nil
+	}
+	if prev == nil {
+		h.head = t.sibling
+	} else {
+		prev.sibling = t.sibling
+	}
+
+	// the children are ordered by decreasing degree, reverse them
+	var children *tree[T]
+	for c := t.child; c != nil; {
+		next := c.sibling
+		c.parent, c.sibling = nil, children
+		children = c
+		c = next
+	}
+	h.head = h.union(h.head, children)
+	h.n--
+
+	x := t.node
+	x.tree = nil
+	return x
+}
+
+// Meld moves all the elements of the other heap to this heap. The other heap
+// is left empty, the handles of its elements stay valid in this heap.
+// Both heaps must use the same compare function.
+// The complexity is O(log(n)) where n is the length of the resulting heap.
+func (h *Heap[T]) Meld(other *Heap[T]) {
+	if h == other {
+		return
+	}
+	h.head = h.union(h.head, other.head)
+	h.n += other.n
+	other.head, other.n = nil, 0
+}
+
+// DecreaseKey sets the element of the node to elem, which must not go after
+// the current element.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap[T]) DecreaseKey(x *Node[T], elem *T) {
+	if h.compar(elem, &x.Elem) > 0 {
+		panic("DecreaseKey: the new element goes after the old one")
+	}
+	x.Elem = *elem
+
+	t := x.tree
+	for t.parent != nil && h.compar(&t.node.Elem, &t.parent.node.Elem) < 0 {
+		p := t.parent
+		t.node, p.node = p.node, t.node
+		t.node.tree, p.node.tree = t, p
+		t = p
+	}
+}
+
+// top finds the top root and the root before it.
+func (h *Heap[T]) top() (prev, t *tree[T]) {
+	var p *tree[T]
+	for r := h.head; r != nil; p, r = r, r.sibling {
+		if t == nil || h.compar(&r.node.Elem, &t.node.Elem) < 0 {
+			prev, t = p, r
+		}
+	}
+	return prev, t
+}
+
+// union merges two root lists and links the roots of an equal degree.
+func (h *Heap[T]) union(a, b *tree[T]) *tree[T] {
+	head := merge(a, b)
+	if head == nil {
+		return nil
+	}
+
+	var prev *tree[T]
+	x := head
+	for next := x.sibling; next != nil; next = x.sibling {
+		if x.degree != next.degree ||
+			(next.sibling != nil && next.sibling.degree == x.degree) {
+			prev, x = x, next
+		} else if h.compar(&x.node.Elem, &next.node.Elem) <= 0 {
+			x.sibling = next.sibling
+			link(next, x)
+		} else {
+			if prev == nil {
+				head = next
+			} else {
+				prev.sibling = next
+			}
+			link(x, next)
+			x = next
+		}
+	}
+	return head
+}
+
+// merge merges two root lists by increasing degree.
+func merge[T any](a, b *tree[T]) *tree[T] {
+	var head tree[T]
+	tail := &head
+	for a != nil && b != nil {
+		if a.degree <= b.degree {
+			tail.sibling, a = a, a.sibling
+		} else {
+			tail.sibling, b = b, b.sibling
+		}
+		tail = tail.sibling
+	}
+	if a != nil {
+		tail.sibling = a
+	} else {
+		tail.sibling = b
+	}
+	return head.sibling
+}
+
+// link makes the root y a child of the root z of the same degree.
+func link[T any](y, z *tree[T]) {
+	y.parent = z
+	y.sibling = z.child
+	z.child = y
+	z.degree++
+}
@@ -0,0 +1,116 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Int32(a, b *int32) int {
+	return int(*a) - int(*b)
+}
+
+func drain(t *testing.T, h *Heap[int32], want []int32) {
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+	if h.Len() != len(want) {
+		t.Fatalf("Len() = %d; want %d", h.Len(), len(want))
+	}
+	for i := range want {
+		if x := h.Top(); x == nil || x.Elem != want[i] {
+			t.Fatalf("%d.th top got %v; want %d", i, x, want[i])
+		}
+		if x := h.Pop(); x.Elem != want[i] {
+			t.Fatalf("%d.th pop got %d; want %d", i, x.Elem, want[i])
+		}
+	}
+	if h.Pop() != nil || h.Top() != nil || h.Len() != 0 {
+		t.Errorf("heap not empty")
+	}
+}
+
+func TestPushPop(t *testing.T) {
+	h := New(Int32)
+	var want []int32
+	for i := 0; i < 1000; i++ {
+		x := int32(rand.Intn(100))
+		h.Push(&x)
+		want = append(want, x)
+	}
+	drain(t, h, want)
+}
+
+func TestInterleaved(t *testing.T) {
+	h := New(Int32)
+	for i := int32(0); i < 100; i++ {
+		x, y := 2*i+1, 2*i
+		h.Push(&x)
+		h.Push(&y)
+		if x := h.Pop(); x.Elem != i {
+			t.Fatalf("pop got %d; want %d", x.Elem, i)
+		}
+	}
+}
+
+func TestDecreaseKey(t *testing.T) {
+	h := New(Int32)
+	var nodes []*Node[int32]
+	for i := int32(0); i < 500; i++ {
+		x := 1000 + i
+		nodes = append(nodes, h.Push(&x))
+	}
+	h.Pop()
+	nodes = nodes[1:]
+
+	want := make([]int32, len(nodes))
+	for i, x := range nodes {
+		want[i] = x.Elem
+	}
+	for _, i := range rand.Perm(len(nodes))[:200] {
+		y := want[i] - int32(rand.Intn(2000))
+		h.DecreaseKey(nodes[i], &y)
+		want[i] = y
+	}
+	drain(t, h, want)
+}
+
+func TestDecreaseKeyIncrease(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("increased key accepted")
+		}
+	}()
+	h := New(Int32)
+	x, y := int32(1), int32(2)
+	h.DecreaseKey(h.Push(&x), &y)
+}
+
+func TestMeld(t *testing.T) {
+	a, b := New(Int32), New(Int32)
+	var want []int32
+	var nodes []*Node[int32]
+	for i := 0; i < 300; i++ {
+		x := int32(rand.Intn(1000))
+		if i%3 == 0 {
+			nodes = append(nodes, a.Push(&x))
+		} else {
+			nodes = append(nodes, b.Push(&x))
+		}
+		want = append(want, x)
+	}
+	a.Meld(b)
+	if b.Len() != 0 || b.Top() != nil {
+		t.Errorf("melded heap not empty")
+	}
+
+	// the handles stay valid after the meld
+	for i, x := range nodes {
+		y := x.Elem - 1
+		a.DecreaseKey(x, &y)
+		want[i] = y
+	}
+	drain(t, a, want)
+}
@@ -0,0 +1,235 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a calendar queue (a bucketed priority queue with a
+// dynamic resizing) for the discrete-event simulation.
+//
+// The records are ordered by a timestamp key. The key range is split into
+// the days of a year, every day is a bucket of the records sorted by the
+// compare function. Push and Pop are O(1) on average when the timestamps
+// are spread fairly evenly.
+package heap
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	minBuckets = 2
+	samples    = 25 // records sampled to estimate the bucket width
+	maxCost    = 8  // days stepped and records shifted per operation
+)
+
+// Queue is a calendar queue. The zero value is not usable, use New.
+type Queue[T any] struct {
+	compar func(*T, *T) int
+	key    func(*T) float64
+
+	buckets [][]T   // days, every day sorted in the reverse order
+	width   float64 // length of a day
+	last    int     // day of the last popped record
+	top     float64 // end of the last day in the current year
+	n       int
+
+	// the cost of the operations since the last resize, the width is
+	// estimated again when the cost per operation grows
+	cost, ops int
+}
+
+// New returns an empty queue.
+// The compar is a compare function, it must order the records by the key
+// first.
+// The key returns the timestamp of a record.
+func New[T any](compar func(*T, *T) int, key func(*T) float64) *Queue[T] {
+	q := &Queue[T]{compar: compar, key: key, width: 1}
+	q.buckets = make([][]T, minBuckets)
+	q.at(0)
+	return q
+}
+
+// Len returns the number of the records in the queue.
+func (q *Queue[T]) Len() int {
+	return q.n
+}
+
+// Push pushes the record onto the queue.
+// It panics when the key is NaN or infinite, such a record has no day.
+// The complexity is O(1) on average.
+func (q *Queue[T]) Push(elem *T) {
+	k := q.key(elem)
+	if math.IsNaN(k) || math.IsInf(k, 0) {
+		panic("Push: key is not finite")
+	}
+	q.insert(elem, k)
+	q.n++
+
+	// a record before the current day moves the calendar back
+	if k < q.top-q.width {
+		q.at(k)
+	}
+	if q.n > 2*len(q.buckets) {
+		q.resize(2 * len(q.buckets))
+		return
+	}
+	q.tune()
+}
+
+// Peek returns the top record without removing it, nil if the queue is empty.
+// The complexity is O(1) on average.
+func (q *Queue[T]) Peek() *T {
+	if q.n == 0 {
+		return nil
+	}
+	b := q.buckets[q.find()]
+	return &b[len(b)-1]
+}
+
+// Pop removes the top record and returns it.
+// The ok is false if the queue is empty.
+// The complexity is O(1) on average.
+func (q *Queue[T]) Pop() (x T, ok bool) {
+	if q.n == 0 {
+		return x, false
+	}
+	x = q.pop()
+	if q.n < len(q.buckets)/2 && len(q.buckets) > minBuckets {
+		q.resize(len(q.buckets) / 2)
+		return x, true
+	}
+	q.tune()
+	return x, true
+}
+
+// tune estimates the width again when the operations got expensive, the
+// cost is measured over a window of len(q.buckets) operations.
+func (q *Queue[T]) tune() {
+	if q.cost > maxCost*len(q.buckets) {
+		q.resize(len(q.buckets))
+		return
+	}
+	if q.ops++; q.ops >= len(q.buckets) {
+		q.cost, q.ops = 0, 0
+	}
+}
+
+// pop removes the top record without resizing.
+func (q *Queue[T]) pop() T {
+	i := q.find()
+	b := q.buckets[i]
+	x := b[len(b)-1]
+	var zero T
+	b[len(b)-1] = zero
+	q.buckets[i] = b[:len(b)-1]
+	q.n--
+	return x
+}
+
+// find moves the calendar to the day of the top record and returns the day.
+func (q *Queue[T]) find() int {
+	i, top := q.last, q.top
+	for range q.buckets {
+		if b := q.buckets[i]; len(b) > 0 && q.key(&b[len(b)-1]) < top {
+			q.last, q.top = i, top
+			return i
+		}
+		if i++; i == len(q.buckets) {
+			i = 0
+		}
+		top += q.width
+		q.cost++
+	}
+
+	// no record this year, search directly
+	q.cost += len(q.buckets)
+	m := -1
+	for i, b := range q.buckets {
+		if len(b) > 0 && (m < 0 || q.compar(&b[len(b)-1], &q.buckets[m][len(q.buckets[m])-1]) < 0) {
+			m = i
+		}
+	}
+	b := q.buckets[m]
+	q.at(q.key(&b[len(b)-1]))
+	return m
+}
+
+// at moves the calendar to the day of the key.
+func (q *Queue[T]) at(k float64) {
+	day := math.Floor(k / q.width)
+	q.last = q.day(k)
+	q.top = (day + 1) * q.width
+}
+
+func (q *Queue[T]) day(k float64) int {
+	d := math.Mod(math.Floor(k/q.width), float64(len(q.buckets)))
+	if d < 0 {
+		d += float64(len(q.buckets))
+	}
+	return int(d)
+}
+
+// insert inserts the record into its day.
+func (q *Queue[T]) insert(elem *T, k float64) {
+	d := q.day(k)
+	b := q.buckets[d]
+	i := sort.Search(len(b), func(i int) bool {
+		return q.compar(&b[i], elem) <= 0
+	})
+	var zero T
+	b = append(b, zero)
+	copy(b[i+1:], b[i:])
+	q.cost += len(b) - 1 - i
+	b[i] = *elem
+	q.buckets[d] = b
+}
+
+// resize rebuilds the calendar with n days, the length of a day is estimated
+// from the separation of the top records.
+func (q *Queue[T]) resize(n int) {
+	var sample []T
+	for len(sample) < samples && q.n > 0 {
+		sample = append(sample, q.pop())
+	}
+	if w := width(sample, q.key); w > 0 {
+		q.width = w
+	}
+
+	old := q.buckets
+	q.buckets = make([][]T, n)
+	q.cost, q.ops = 0, 0
+	for _, b := range old {
+		for i := range b {
+			q.insert(&b[i], q.key(&b[i]))
+		}
+	}
+	for i := range sample {
+		q.insert(&sample[i], q.key(&sample[i]))
+	}
+	q.n += len(sample)
+	if len(sample) > 0 {
+		q.at(q.key(&sample[0]))
+	} else {
+		q.at(0)
+	}
+}
+
+// width returns three times the average separation of the sorted keys,
+// ignoring the separations more than twice the average.
+func width[T any](sample []T, key func(*T) float64) float64 {
+	if len(sample) < 2 {
+		return 0
+	}
+	avg := (key(&sample[len(sample)-1]) - key(&sample[0])) / float64(len(sample)-1)
+	sum, m := 0.0, 0
+	for i := 1; i < len(sample); i++ {
+		if d := key(&sample[i]) - key(&sample[i-1]); d <= 2*avg {
+			sum += d
+			m++
+		}
+	}
+	if m == 0 || sum == 0 {
+		return 0
+	}
+	return 3 * sum / float64(m)
+}
@@ -0,0 +1,148 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+type event struct {
+	At float64
+	ID uint64
+}
+
+func Event(a, b *event) int {
+	switch {
+	case a.At < b.At:
+		return -1
+	case a.At > b.At:
+		return 1
+	case a.ID < b.ID:
+		return -1
+	case a.ID > b.ID:
+		return 1
+	}
+	return 0
+}
+
+func At(e *event) float64 {
+	return e.At
+}
+
+func TestPushPop(t *testing.T) {
+	for _, spread := range []float64{0, 1, 1000, 1e9} {
+		q := New(Event, At)
+		var want []event
+		for i := 0; i < 2000; i++ {
+			x := event{rand.Float64()*spread - spread/2, uint64(i)}
+			q.Push(&x)
+			want = append(want, x)
+		}
+		sort.Slice(want, func(i, j int) bool { return Event(&want[i], &want[j]) < 0 })
+
+		for i := range want {
+			if p := q.Peek(); p == nil || *p != want[i] {
+				t.Fatalf("spread %g: %d.th peek got %v; want %v", spread, i, p, want[i])
+			}
+			if x, ok := q.Pop(); !ok || x != want[i] {
+				t.Fatalf("spread %g: %d.th pop got %v; want %v", spread, i, x, want[i])
+			}
+		}
+		if _, ok := q.Pop(); ok || q.Peek() != nil || q.Len() != 0 {
+			t.Errorf("queue not empty")
+		}
+	}
+}
+
+// TestEarlier pushes records before the last popped one.
+func TestEarlier(t *testing.T) {
+	q := New(Event, At)
+	ref := []event{}
+	for i := 0; i < 5000; i++ {
+		x := event{rand.Float64() * 100, uint64(i)}
+		if i%7 == 0 {
+			x.At -= 200
+		}
+		q.Push(&x)
+		uheap.Push(Event, &ref, &x)
+		if i%3 == 0 {
+			x, _ := q.Pop()
+			if x != ref[0] {
+				t.Fatalf("pop got %v; want %v", x, ref[0])
+			}
+			uheap.Remove(Event, &ref, 0)
+		}
+	}
+	for len(ref) > 0 {
+		if x, _ := q.Pop(); x != ref[0] {
+			t.Fatalf("pop got %v; want %v", x, ref[0])
+		}
+		uheap.Remove(Event, &ref, 0)
+	}
+}
+
+func TestNotFinite(t *testing.T) {
+	q := New(Event, At)
+	for _, k := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("key %v accepted", k)
+				}
+			}()
+			q.Push(&event{k, 0})
+		}()
+	}
+	if q.Len() != 0 {
+		t.Errorf("Len() = %d; want 0", q.Len())
+	}
+}
+
+// the hold model: pop the next event, schedule a new one a random time later,
+// the first 2*n holds reach the steady state
+func hold(b *testing.B, n int, push func(*event), pop func() event) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < n; i++ {
+		push(&event{r.ExpFloat64(), uint64(i)})
+	}
+	for i := 0; i < b.N+2*n; i++ {
+		if i == 2*n {
+			b.ResetTimer()
+		}
+		x := pop()
+		x.At += r.ExpFloat64() * float64(n)
+		push(&x)
+	}
+}
+
+func benchmarkCalendar(b *testing.B, n int) {
+	q := New(Event, At)
+	hold(b, n, q.Push, func() event {
+		x, _ := q.Pop()
+		return x
+	})
+}
+
+func benchmarkBinary(b *testing.B, n int) {
+	h := []event{}
+	hold(b, n, func(x *event) {
+		uheap.Push(Event, &h, x)
+	}, func() event {
+		x := h[0]
+		uheap.Remove(Event, &h, 0)
+		return x
+	})
+}
+
+func BenchmarkCalendar1e3(b *testing.B) { benchmarkCalendar(b, 1e3) }
+func BenchmarkCalendar1e5(b *testing.B) { benchmarkCalendar(b, 1e5) }
+func BenchmarkCalendar1e6(b *testing.B) { benchmarkCalendar(b, 1e6) }
+func BenchmarkBinary1e3(b *testing.B)   { benchmarkBinary(b, 1e3) }
+func BenchmarkBinary1e5(b *testing.B)   { benchmarkBinary(b, 1e5) }
+func BenchmarkBinary1e6(b *testing.B)   { benchmarkBinary(b, 1e6) }
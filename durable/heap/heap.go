@@ -0,0 +1,399 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a crash-safe priority queue of fixed-size records.
+//
+// The queue is an unsafe/heap slice kept in memory. Every Push, Remove and
+// Fix is appended to a write-ahead log before it is applied, the heap slice
+// is checkpointed from time to time. Open recovers the queue by loading the
+// checkpoint and replaying the log, a torn record at the end of the log is
+// dropped.
+//
+// The heap operations are deterministic, the replay must use the same compare
+// function as the logged operations did.
+package heap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"unsafe"
+)
+
+// The files in the queue directory.
+const (
+	CheckpointFile = "checkpoint"
+	LogFile        = "log"
+)
+
+// The checkpoint is a generation number followed by the encoded heap:
+//
+//	magic      [4]byte "GMCP"
+//	generation uint64
+//	heap       unsafe/heap encoding, heap-ordered
+//
+// The log is a header followed by the records of the operations:
+//
+//	magic      [4]byte "GMWL"
+//	generation uint64  of the checkpoint the log applies to
+//	size       uint32  element size in bytes
+//
+//	op         uint8   opPush, opRemove or opFix
+//	index      uint64  unused by opPush
+//	elem       [size]byte in the host byte order, zero for opRemove
+//	crc        uint32  IEEE CRC-32 of the op, index and elem
+//
+// A checkpoint of the generation g+1 includes all the operations logged
+// since the generation g. The log is restarted after the checkpoint, a log
+// of an older generation left behind by a crash is ignored.
+const (
+	checkpointMagic = "GMCP"
+	logMagic        = "GMWL"
+	logHeaderSize   = 16
+
+	opPush   = 1
+	opRemove = 2
+	opFix    = 3
+)
+
+// DefaultEvery is the default number of the logged operations between two
+// automatic checkpoints.
+const DefaultEvery = 4096
+
+// ErrCorrupt is returned by Open when the checkpoint cannot be loaded.
+var ErrCorrupt = errors.New("heap: corrupt checkpoint")
+
+// Queue is a crash-safe priority queue of fixed-size records.
+// The T must not contain pointers.
+// A failed log write is cut off the log, the operation is not applied. When
+// the log cannot be restored or synced, or the checkpoint cannot be synced,
+// the queue is broken: every later operation returns the error, reopen the
+// queue to get the recovered state.
+type Queue[T any] struct {
+	// Every is the number of the logged operations between two automatic
+	// checkpoints, 0 disables the automatic checkpoints.
+	Every int
+
+	compar     func(*T, *T) int
+	dir        string
+	heap       []T
+	log        *os.File
+	off        int64 // the end of the last good record of the log
+	err        error // the queue is broken
+	logged     int
+	generation uint64
+	buf        []byte
+}
+
+// Open opens the queue in the directory dir, creating it if needed, and
+// recovers its contents.
+// The compar is a compare function.
+func Open[T any](dir string, compar func(*T, *T) int) (*Queue[T], error) {
+	var zero T
+	if !uheap.Plain(reflect.TypeOf(zero)) || unsafe.Sizeof(zero) == 0 {
+		return nil, fmt.Errorf("heap: %T is not a fixed-size record", zero)
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, err
+	}
+	q := &Queue[T]{Every: DefaultEvery, compar: compar, dir: dir}
+	q.buf = make([]byte, q.recordSize())
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	if err := q.replay(); err != nil {
+		if q.log != nil {
+			q.log.Close()
+		}
+		return nil, err
+	}
+	return q, nil
+}
+
+// Len returns the number of the elements in the queue.
+func (q *Queue[T]) Len() int {
+	return len(q.heap)
+}
+
+// Slice returns the heapified slice of the queue, Slice()[0] is the top.
+// The slice must not be modified, it is valid until the next operation.
+func (q *Queue[T]) Slice() []T {
+	return q.heap
+}
+
+// Push pushes the element onto the queue.
+// The complexity is O(log(n)) plus a synchronous log write.
+func (q *Queue[T]) Push(elem *T) error {
+	if q.err != nil {
+		return q.err
+	}
+	if err := q.append(opPush, 0, elem); err != nil {
+		return err
+	}
+	uheap.Push(q.compar, &q.heap, elem)
+	return q.logged1()
+}
+
+// Remove removes the element at index i from the queue.
+// The complexity is O(log(n)) plus a synchronous log write.
+func (q *Queue[T]) Remove(i int) error {
+	if q.err != nil {
+		return q.err
+	}
+	if i < 0 || i >= len(q.heap) {
+		return fmt.Errorf("heap: index %d out of range", i)
+	}
+	var zero T
+	if err := q.append(opRemove, i, &zero); err != nil {
+		return err
+	}
+	uheap.Remove(q.compar, &q.heap, i)
+	return q.logged1()
+}
+
+// Fix replaces the element at index i with elem and re-establishes the heap
+// ordering.
+// The complexity is O(log(n)) plus a synchronous log write.
+func (q *Queue[T]) Fix(i int, elem *T) error {
+	if q.err != nil {
+		return q.err
+	}
+	if i < 0 || i >= len(q.heap) {
+		return fmt.Errorf("heap: index %d out of range", i)
+	}
+	if err := q.append(opFix, i, elem); err != nil {
+		return err
+	}
+	q.heap[i] = *elem
+	uheap.Fix(q.compar, q.heap, i)
+	return q.logged1()
+}
+
+// Checkpoint writes the heap slice to the checkpoint and restarts the log.
+func (q *Queue[T]) Checkpoint() error {
+	if q.err != nil {
+		return q.err
+	}
+	name := filepath.Join(q.dir, CheckpointFile)
+	f, err := os.Create(name + ".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	var b [12]byte
+	copy(b[:], checkpointMagic)
+	binary.LittleEndian.PutUint64(b[4:], q.generation+1)
+	w.Write(b[:])
+	err = uheap.Encode(w, q.heap, true)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(name+".tmp", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the checkpoint of the next generation is in place, the old log must
+	// not be appended to nor restarted until it is durable
+	q.generation++
+	if err := syncDir(q.dir); err != nil {
+		q.err = err
+		return err
+	}
+	if err := q.restart(); err != nil {
+		q.err = err
+		return err
+	}
+	return nil
+}
+
+// Close checkpoints the queue and closes the log. A broken queue is closed
+// without a checkpoint.
+func (q *Queue[T]) Close() error {
+	err := q.Checkpoint()
+	if cerr := q.log.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (q *Queue[T]) logged1() error {
+	q.logged++
+	if q.Every > 0 && q.logged >= q.Every {
+		return q.Checkpoint()
+	}
+	return nil
+}
+
+func (q *Queue[T]) recordSize() int {
+	var zero T
+	return 1 + 8 + int(unsafe.Sizeof(zero)) + 4
+}
+
+// append writes one record to the log and syncs it. A torn write is cut off,
+// so that the later records are not lost behind it on replay.
+func (q *Queue[T]) append(op byte, i int, elem *T) error {
+	b := q.buf
+	b[0] = op
+	binary.LittleEndian.PutUint64(b[1:], uint64(i))
+	copy(b[9:], bytesOf(elem))
+	binary.LittleEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(b[:len(b)-4]))
+	if _, err := q.log.Write(b); err != nil {
+		if terr := q.truncate(q.off); terr != nil {
+			q.err = terr
+		}
+		return err
+	}
+	if err := q.log.Sync(); err != nil {
+		q.err = err // the record may or may not be durable
+		return err
+	}
+	q.off += int64(len(b))
+	return nil
+}
+
+// truncate cuts the log at the offset and moves the end of the log there.
+func (q *Queue[T]) truncate(off int64) error {
+	if err := q.log.Truncate(off); err != nil {
+		return err
+	}
+	_, err := q.log.Seek(off, io.SeekStart)
+	q.off = off
+	return err
+}
+
+// load loads the checkpoint, if any.
+func (q *Queue[T]) load() error {
+	f, err := os.Open(filepath.Join(q.dir, CheckpointFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var b [12]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil || string(b[:4]) != checkpointMagic {
+		return ErrCorrupt
+	}
+	q.generation = binary.LittleEndian.Uint64(b[4:])
+	if err := uheap.Decode(r, q.compar, &q.heap); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return nil
+}
+
+// replay applies the log of the current generation and leaves it open for
+// appending, the torn tail of the log is truncated.
+func (q *Queue[T]) replay() error {
+	f, err := os.OpenFile(filepath.Join(q.dir, LogFile), os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return err
+	}
+	q.log = f
+
+	var h [logHeaderSize]byte
+	if _, err := io.ReadFull(f, h[:]); err != nil || string(h[:4]) != logMagic {
+		// an empty or torn log
+		return q.restart()
+	}
+	if binary.LittleEndian.Uint32(h[12:]) != uint32(len(q.buf)-13) {
+		return fmt.Errorf("heap: log element size %d, want %d",
+			binary.LittleEndian.Uint32(h[12:]), len(q.buf)-13)
+	}
+	if binary.LittleEndian.Uint64(h[4:]) != q.generation {
+		// a stale log, the checkpoint includes it
+		return q.restart()
+	}
+
+	r := bufio.NewReader(f)
+	good := int64(logHeaderSize)
+	for {
+		b := q.buf
+		if _, err := io.ReadFull(r, b); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(b[:len(b)-4]) != binary.LittleEndian.Uint32(b[len(b)-4:]) {
+			break
+		}
+		if !q.apply(b[0], binary.LittleEndian.Uint64(b[1:]), b[9:len(b)-4]) {
+			break
+		}
+		good += int64(len(b))
+		q.logged++
+	}
+
+	return q.truncate(good)
+}
+
+func (q *Queue[T]) apply(op byte, i uint64, elem []byte) bool {
+	var x T
+	copy(bytesOf(&x), elem)
+	switch {
+	case op == opPush:
+		uheap.Push(q.compar, &q.heap, &x)
+	case op == opRemove && i < uint64(len(q.heap)):
+		uheap.Remove(q.compar, &q.heap, int(i))
+	case op == opFix && i < uint64(len(q.heap)):
+		q.heap[i] = x
+		uheap.Fix(q.compar, q.heap, int(i))
+	default:
+		return false
+	}
+	return true
+}
+
+// restart empties the log and writes the header of the current generation.
+func (q *Queue[T]) restart() error {
+	if err := q.log.Truncate(0); err != nil {
+		return err
+	}
+	var h [logHeaderSize]byte
+	copy(h[:], logMagic)
+	binary.LittleEndian.PutUint64(h[4:], q.generation)
+	binary.LittleEndian.PutUint32(h[12:], uint32(len(q.buf)-13))
+	if _, err := q.log.WriteAt(h[:], 0); err != nil {
+		return err
+	}
+	if _, err := q.log.Seek(logHeaderSize, io.SeekStart); err != nil {
+		return err
+	}
+	q.off = logHeaderSize
+	q.logged = 0
+	return q.log.Sync()
+}
+
+func bytesOf[T any](elem *T) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(elem)), unsafe.Sizeof(*elem))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
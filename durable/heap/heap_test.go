@@ -0,0 +1,288 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"encoding/binary"
+	"errors"
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type event struct {
+	At uint32
+	ID uint16
+	_  [2]byte
+}
+
+func Event(a, b *event) int {
+	if a.At != b.At {
+		return int(a.At) - int(b.At)
+	}
+	return int(a.ID) - int(b.ID)
+}
+
+// run applies random operations to the queue and returns the heap slice
+// after every operation.
+func run(t *testing.T, q *Queue[event], n int, r *rand.Rand) (states [][]event) {
+	for i := 0; i < n; i++ {
+		var err error
+		switch l := q.Len(); {
+		case l > 0 && r.Intn(4) == 0:
+			err = q.Remove(r.Intn(l))
+		case l > 0 && r.Intn(4) == 0:
+			err = q.Fix(r.Intn(l), &event{At: uint32(r.Intn(1000)), ID: uint16(i)})
+		default:
+			err = q.Push(&event{At: uint32(r.Intn(1000)), ID: uint16(i)})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		states = append(states, append([]event(nil), q.Slice()...))
+	}
+	return states
+}
+
+func same(a, b []event) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(dir, Event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Every = 7
+	states := run(t, q, 100, rand.New(rand.NewSource(1)))
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err = Open(dir, Event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if !same(q.Slice(), states[len(states)-1]) {
+		t.Errorf("Has %v", q.Slice())
+	}
+
+	// the recovered queue is heap-ordered and keeps working
+	for q.Len() > 0 {
+		x := q.Slice()[0]
+		if err := q.Remove(0); err != nil {
+			t.Fatal(err)
+		}
+		if q.Len() > 0 && Event(&q.Slice()[0], &x) < 0 {
+			t.Fatalf("popped %v before %v", x, q.Slice()[0])
+		}
+	}
+}
+
+// TestCrash simulates a crash at every byte of the log: the queue is dropped
+// without Close and the log is truncated. The recovered queue must hold the
+// state after the last complete record.
+func TestCrash(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(dir, Event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Every = 0
+	r := rand.New(rand.NewSource(2))
+	run(t, q, 20, r)
+	if err := q.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	base := append([]event(nil), q.Slice()...)
+	states := run(t, q, 30, r)
+	q.log.Close() // crash
+
+	logData, err := os.ReadFile(filepath.Join(dir, LogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, err := os.ReadFile(filepath.Join(dir, CheckpointFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := q.recordSize()
+
+	for n := 0; n <= len(logData); n++ {
+		crash := t.TempDir()
+		os.WriteFile(filepath.Join(crash, CheckpointFile), checkpoint, 0o666)
+		os.WriteFile(filepath.Join(crash, LogFile), logData[:n], 0o666)
+
+		q, err := Open(crash, Event)
+		if err != nil {
+			t.Fatalf("Open with %d log bytes: %v", n, err)
+		}
+		want := base
+		if k := (n - logHeaderSize) / size; n >= logHeaderSize && k > 0 {
+			want = states[k-1]
+		}
+		if !same(q.Slice(), want) {
+			t.Fatalf("%d log bytes: has %v; want %v", n, q.Slice(), want)
+		}
+
+		// the torn tail is dropped, the new records follow the good ones
+		x := event{At: 5}
+		if err := q.Push(&x); err != nil {
+			t.Fatal(err)
+		}
+		want = append([]event(nil), q.Slice()...)
+		q.log.Close() // crash again
+
+		q, err = Open(crash, Event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !same(q.Slice(), want) {
+			t.Fatalf("%d log bytes, pushed: has %v; want %v", n, q.Slice(), want)
+		}
+		q.log.Close()
+	}
+}
+
+func TestCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	q, _ := Open(dir, Event)
+	q.Every = 0
+	states := run(t, q, 10, rand.New(rand.NewSource(3)))
+	q.log.Close()
+
+	// flip a bit in the 6th record, the first 5 records survive
+	name := filepath.Join(dir, LogFile)
+	data, _ := os.ReadFile(name)
+	data[logHeaderSize+5*q.recordSize()+3] ^= 1
+	os.WriteFile(name, data, 0o666)
+
+	q, err := Open(dir, Event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if !same(q.Slice(), states[4]) {
+		t.Errorf("Has %v; want %v", q.Slice(), states[4])
+	}
+}
+
+// TestStaleLog simulates a crash after a checkpoint was written and before
+// the log was restarted: the old log must not be replayed again.
+func TestStaleLog(t *testing.T) {
+	dir := t.TempDir()
+	q, _ := Open(dir, Event)
+	q.Every = 0
+	states := run(t, q, 10, rand.New(rand.NewSource(4)))
+	q.log.Close()
+
+	name := filepath.Join(dir, LogFile)
+	stale, _ := os.ReadFile(name)
+
+	q, _ = Open(dir, Event)
+	if err := q.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	q.log.Close()
+	os.WriteFile(name, stale, 0o666)
+
+	q, err := Open(dir, Event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if !same(q.Slice(), states[9]) {
+		t.Errorf("Has %v; want %v", q.Slice(), states[9])
+	}
+}
+
+func TestPointers(t *testing.T) {
+	if _, err := Open(t.TempDir(), func(a, b **int) int { return 0 }); err == nil {
+		t.Errorf("a pointer record accepted")
+	}
+}
+
+func TestCheckpointEncoding(t *testing.T) {
+	dir := t.TempDir()
+	q, _ := Open(dir, Event)
+	run(t, q, 10, rand.New(rand.NewSource(5)))
+	want := append([]event(nil), q.Slice()...)
+	q.Close()
+
+	// the checkpoint holds an unsafe/heap encoding after the generation
+	f, err := os.Open(filepath.Join(dir, CheckpointFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Seek(12, 0)
+	var h []event
+	if err := uheap.Decode(f, nil, &h); err != nil {
+		t.Fatal(err)
+	}
+	if !same(h, want) {
+		t.Errorf("Has %v; want %v", h, want)
+	}
+}
+
+// TestBroken fails a log write: the operation is not applied, the queue
+// refuses the later operations and the reopened queue has the acknowledged
+// ones.
+func TestBroken(t *testing.T) {
+	dir := t.TempDir()
+	q, _ := Open(dir, Event)
+	q.Every = 0
+	states := run(t, q, 10, rand.New(rand.NewSource(6)))
+
+	ro, err := os.Open(filepath.Join(dir, LogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.log.Close()
+	q.log = ro
+	if err := q.Push(&event{At: 1}); err == nil {
+		t.Fatal("a failed log write acknowledged")
+	}
+	if !same(q.Slice(), states[9]) {
+		t.Errorf("Has %v; want %v", q.Slice(), states[9])
+	}
+	if err := q.Push(&event{At: 2}); err == nil {
+		t.Error("a broken queue acknowledged a push")
+	}
+	if err := q.Close(); err == nil {
+		t.Error("a broken queue checkpointed")
+	}
+
+	q, err = Open(dir, Event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if !same(q.Slice(), states[9]) {
+		t.Errorf("Has %v; want %v", q.Slice(), states[9])
+	}
+}
+
+func TestCorruptCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	q, _ := Open(dir, Event)
+	run(t, q, 10, rand.New(rand.NewSource(7)))
+	q.Close()
+
+	// a huge count in the heap header
+	name := filepath.Join(dir, CheckpointFile)
+	data, _ := os.ReadFile(name)
+	binary.LittleEndian.PutUint64(data[12+12:], 1<<44)
+	os.WriteFile(name, data, 0o666)
+
+	if _, err := Open(dir, Event); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Open error %v; want ErrCorrupt", err)
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides an external-memory priority queue of fixed-size
+// records, for more records than fit in the memory.
+//
+// The queue keeps a bounded unsafe/heap buffer in memory. When the buffer is
+// full it is sorted and spilled to a temporary file as a run, Pop merges the
+// heads of the runs with the buffer lazily. A run is an unsafe/heap encoding
+// of a sorted, and so heap-ordered, slice.
+//
+// The spilled runs are of the level 0. When FanIn runs of a level pile up,
+// they are merged into one run of the next level, so the open files are
+// bounded by FanIn per level.
+package heap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	heap32 "github.com/gomacro/heap/int32/heap"
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// ErrEmpty is returned by Pop on an empty queue.
+var ErrEmpty = errors.New("heap: Pop on an empty queue")
+
+// DefaultFanIn is the default number of the runs merged into one.
+const DefaultFanIn = 64
+
+type run[T any] struct {
+	f     *os.File
+	r     *bufio.Reader
+	head  T
+	left  uint64 // records left after the head
+	level int
+}
+
+// Queue is an external-memory priority queue. The T must not contain
+// pointers.
+// A failed merge or a failed read of the runs breaks the queue, every later
+// operation returns the error.
+type Queue[T any] struct {
+	// FanIn is the number of the runs of a level merged into one run of the
+	// next level, less than 2 disables the merging.
+	FanIn int
+
+	compar func(*T, *T) int
+	dir    string
+	limit  int
+
+	buf   []T       // the in-memory heap
+	runs  []*run[T] // the spilled runs, nil when exhausted
+	heads []int32   // int32/heap of the runs ordered by their heads
+	n     int
+	err   error // the queue is broken
+}
+
+// New returns an empty queue.
+// The compar is a compare function.
+// The limit is the number of the records kept in memory.
+// The dir is the directory of the runs, os.TempDir() if empty.
+func New[T any](compar func(*T, *T) int, limit int, dir string) (*Queue[T], error) {
+	var zero T
+	if !uheap.Plain(reflect.TypeOf(zero)) || unsafe.Sizeof(zero) == 0 {
+		return nil, fmt.Errorf("heap: %T is not a fixed-size record", zero)
+	}
+	if limit < 1 {
+		return nil, errors.New("heap: limit must be positive")
+	}
+	return &Queue[T]{FanIn: DefaultFanIn, compar: compar, dir: dir, limit: limit}, nil
+}
+
+// Len returns the number of the records in the queue.
+func (q *Queue[T]) Len() int {
+	return q.n
+}
+
+// Runs returns the number of the runs on the disk.
+func (q *Queue[T]) Runs() int {
+	return len(q.heads)
+}
+
+// Top returns the top record without removing it, nil if the queue is empty.
+// The complexity is O(1).
+func (q *Queue[T]) Top() *T {
+	if r := q.top(); r != nil {
+		return &r.head
+	}
+	if len(q.buf) > 0 {
+		return &q.buf[0]
+	}
+	return nil
+}
+
+// Push pushes the element onto the queue. A full buffer is spilled first.
+// The complexity is O(log(limit)) amortized plus the spilling.
+func (q *Queue[T]) Push(elem *T) error {
+	if q.err != nil {
+		return q.err
+	}
+	if len(q.buf) == q.limit {
+		if err := q.spill(); err != nil {
+			return err
+		}
+		if err := q.merge(0); err != nil {
+			q.err = err
+			return err
+		}
+	}
+	uheap.Push(q.compar, &q.buf, elem)
+	q.n++
+	return nil
+}
+
+// Pop removes the top record and returns it. A failed read of a run breaks
+// the queue, the read may have consumed a part of a record.
+// The complexity is O(log(limit) + log(runs)) plus the reading.
+func (q *Queue[T]) Pop() (x T, err error) {
+	if q.err != nil {
+		return x, q.err
+	}
+	r := q.top()
+	if r == nil {
+		if len(q.buf) == 0 {
+			return x, ErrEmpty
+		}
+		x = q.buf[0]
+		uheap.Remove(q.compar, &q.buf, 0)
+		q.n--
+		return x, nil
+	}
+
+	x = r.head
+	if r.left == 0 {
+		i := q.heads[0]
+		heap32.Remove(q.byHead, &q.heads, 0)
+		q.runs[i] = nil
+		err = r.close()
+	} else {
+		if err := r.next(); err != nil {
+			var zero T
+			q.err = err
+			return zero, err
+		}
+		heap32.Fix(q.byHead, q.heads, 0)
+	}
+	q.n--
+	return x, err
+}
+
+// Close removes the runs, the queue is left empty.
+func (q *Queue[T]) Close() (err error) {
+	for _, r := range q.runs {
+		if r != nil {
+			if cerr := r.close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	q.buf, q.runs, q.heads, q.n, q.err = nil, nil, nil, 0, nil
+	return err
+}
+
+// top returns the run with the top head if it goes before the buffer.
+func (q *Queue[T]) top() *run[T] {
+	if len(q.heads) == 0 {
+		return nil
+	}
+	r := q.runs[q.heads[0]]
+	if len(q.buf) > 0 && q.compar(&q.buf[0], &r.head) < 0 {
+		return nil
+	}
+	return r
+}
+
+func (q *Queue[T]) byHead(a, b *int32) int {
+	return q.compar(&q.runs[*a].head, &q.runs[*b].head)
+}
+
+// spill writes the sorted buffer to a new run.
+func (q *Queue[T]) spill() error {
+	sort.Slice(q.buf, func(i, j int) bool {
+		return q.compar(&q.buf[i], &q.buf[j]) < 0
+	})
+
+	r, err := q.create(func(w io.Writer) error {
+		return uheap.Encode(w, q.buf, true)
+	})
+	if err != nil {
+		return err
+	}
+
+	i := int32(len(q.runs))
+	q.runs = append(q.runs, r)
+	heap32.Push(q.byHead, &q.heads, &i)
+	q.buf = q.buf[:0]
+	return nil
+}
+
+// merge merges the runs of the level into one run of the next level when
+// there are FanIn of them, and so on up the levels. The merge consumes the
+// runs, a failed merge cannot be undone.
+func (q *Queue[T]) merge(level int) error {
+	if q.FanIn < 2 {
+		return nil
+	}
+	var src []int32 // int32/heap of the runs of the level
+	var count uint64
+	for i, r := range q.runs {
+		if r != nil && r.level == level {
+			src = append(src, int32(i))
+			count += r.left + 1
+		}
+	}
+	if len(src) < q.FanIn {
+		return nil
+	}
+
+	var zero T
+	size := unsafe.Sizeof(zero)
+	r, err := q.create(func(w io.Writer) error {
+		h := uheap.Header{Width: uheap.Width(size), Ordered: true, Size: uint32(size), Count: count}
+		if err := uheap.WriteHeader(w, &h); err != nil {
+			return err
+		}
+		heap32.Heapify(q.byHead, src, src)
+		b := make([]byte, size)
+		for len(src) > 0 {
+			s := q.runs[src[0]]
+			copy(b, unsafe.Slice((*byte)(unsafe.Pointer(&s.head)), size))
+			fromLittleEndian(b, h.Width) // and back
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+			if s.left == 0 {
+				heap32.Remove(q.byHead, &src, 0)
+				continue
+			}
+			if err := s.next(); err != nil {
+				return err
+			}
+			heap32.Fix(q.byHead, src, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.level = level + 1
+
+	// drop the merged runs, the others keep their order
+	var err2 error
+	runs := []*run[T]{r}
+	for _, s := range q.runs {
+		if s == nil {
+			continue
+		}
+		if s.level == level {
+			if cerr := s.close(); err2 == nil {
+				err2 = cerr
+			}
+			continue
+		}
+		runs = append(runs, s)
+	}
+	q.runs, q.heads = runs, q.heads[:0]
+	for i := range runs {
+		q.heads = append(q.heads, int32(i))
+	}
+	heap32.Heapify(q.byHead, q.heads, q.heads)
+	if err2 != nil {
+		return err2
+	}
+	return q.merge(level + 1)
+}
+
+// create writes a new run and reads its first record.
+func (q *Queue[T]) create(write func(w io.Writer) error) (*run[T], error) {
+	f, err := os.CreateTemp(q.dir, "heap-run-")
+	if err != nil {
+		return nil, err
+	}
+	r := &run[T]{f: f}
+	w := bufio.NewWriter(f)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		r.r = bufio.NewReader(f)
+		var h uheap.Header
+		h, err = uheap.ReadHeader(r.r)
+		r.left = h.Count
+	}
+	if err == nil {
+		err = r.next()
+	}
+	if err != nil {
+		r.close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// next reads the next record of the run into the head, a failed read leaves
+// the head as it was but not the reader.
+func (r *run[T]) next() error {
+	var x T
+	b := unsafe.Slice((*byte)(unsafe.Pointer(&x)), unsafe.Sizeof(x))
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	fromLittleEndian(b, uheap.Width(uintptr(len(b))))
+	r.head = x
+	r.left--
+	return nil
+}
+
+func (r *run[T]) close() error {
+	err := r.f.Close()
+	if rerr := os.Remove(r.f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+var bigEndian = binary.NativeEndian.Uint16([]byte{0, 1}) == 1
+
+// fromLittleEndian converts the little-endian words of the encoding to the
+// host byte order, and the other way round.
+func fromLittleEndian(b []byte, width uint8) {
+	if !bigEndian || width == 8 {
+		return
+	}
+	w := int(width / 8)
+	for i := 0; i < len(b); i += w {
+		for j := 0; j < w/2; j++ {
+			b[i+j], b[i+w-1-j] = b[i+w-1-j], b[i+j]
+		}
+	}
+}
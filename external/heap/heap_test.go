@@ -0,0 +1,239 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"testing"
+	"testing/iotest"
+)
+
+type event struct {
+	At  int64
+	Seq uint32
+	_   uint32
+}
+
+func Event(a, b *event) int {
+	switch {
+	case a.At < b.At:
+		return -1
+	case a.At > b.At:
+		return 1
+	}
+	return int(a.Seq) - int(b.Seq)
+}
+
+func Rgb(a, b *[3]byte) int {
+	for i := range a {
+		if r := int(a[i]) - int(b[i]); r != 0 {
+			return r
+		}
+	}
+	return 0
+}
+
+func TestPushPop(t *testing.T) {
+	dir := t.TempDir()
+	q, err := New(Event, 16, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	var want []event
+	for i := 0; i < 5000; i++ {
+		x := event{At: rand.Int63n(1000), Seq: uint32(i)}
+		if err := q.Push(&x); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, x)
+	}
+	if q.Runs() == 0 {
+		t.Errorf("nothing spilled")
+	}
+	sort.Slice(want, func(i, j int) bool { return Event(&want[i], &want[j]) < 0 })
+
+	for i := range want {
+		if top := q.Top(); top == nil || *top != want[i] {
+			t.Fatalf("%d.th top got %v; want %v", i, top, want[i])
+		}
+		x, err := q.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x != want[i] {
+			t.Fatalf("%d.th pop got %v; want %v", i, x, want[i])
+		}
+	}
+	if _, err := q.Pop(); err != ErrEmpty {
+		t.Errorf("Pop on an empty queue: %v", err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("%d runs left", len(files))
+	}
+}
+
+// TestFanIn merges the runs in levels, the open runs stay bounded.
+func TestFanIn(t *testing.T) {
+	dir := t.TempDir()
+	q, _ := New(Event, 4, dir)
+	q.FanIn = 3
+	defer q.Close()
+
+	var want []event
+	for i := 0; i < 5000; i++ {
+		x := event{At: rand.Int63n(1000), Seq: uint32(i)}
+		if err := q.Push(&x); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, x)
+		if q.Runs() >= 3*8 {
+			t.Fatalf("%d runs open after %d pushes", q.Runs(), i+1)
+		}
+		if i%7 == 0 {
+			sort.Slice(want, func(i, j int) bool { return Event(&want[i], &want[j]) < 0 })
+			if x, err := q.Pop(); err != nil || x != want[0] {
+				t.Fatalf("%d.th push: pop got %v, %v; want %v", i, x, err, want[0])
+			}
+			want = want[1:]
+		}
+	}
+	sort.Slice(want, func(i, j int) bool { return Event(&want[i], &want[j]) < 0 })
+	for i := range want {
+		if x, err := q.Pop(); err != nil || x != want[i] {
+			t.Fatalf("%d.th pop got %v, %v; want %v", i, x, err, want[i])
+		}
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("%d runs left", len(files))
+	}
+}
+
+// TestReadError fails the reads of the runs, a failed Pop leaves the queue
+// as it was.
+func TestReadError(t *testing.T) {
+	q, _ := New(Event, 10, t.TempDir())
+	defer q.Close()
+	for i := 0; i < 20; i++ {
+		x := event{At: rand.Int63n(1000), Seq: uint32(i)}
+		q.Push(&x)
+	}
+	want := errors.New("read error")
+	for _, r := range q.runs {
+		r.r = bufio.NewReader(iotest.ErrReader(want))
+	}
+	broken(t, q, want)
+}
+
+// TestTruncatedRun cuts a run in the middle of a record, beyond the buffered
+// part of the run.
+func TestTruncatedRun(t *testing.T) {
+	q, _ := New(Event, 1000, t.TempDir())
+	defer q.Close()
+	for i := 0; i < 1001; i++ {
+		x := event{At: rand.Int63n(1000), Seq: uint32(i)}
+		q.Push(&x)
+	}
+	f := q.runs[0].f
+	fi, _ := f.Stat()
+	if err := f.Truncate(fi.Size() - 5); err != nil {
+		t.Fatal(err)
+	}
+	broken(t, q, io.ErrUnexpectedEOF)
+}
+
+// broken pops until the error, then the queue must stay broken.
+func broken(t *testing.T, q *Queue[event], want error) {
+	for q.Len() > 0 {
+		if _, err := q.Pop(); err != nil {
+			if err != want {
+				t.Fatalf("Pop: %v; want %v", err, want)
+			}
+			if _, err := q.Pop(); err != want {
+				t.Errorf("Pop of a broken queue: %v", err)
+			}
+			if err := q.Push(&event{}); err != want {
+				t.Errorf("Push to a broken queue: %v", err)
+			}
+			return
+		}
+	}
+	t.Error("no read error")
+}
+
+// TestTimestamps pops in between the pushes, the timestamps only grow as in
+// a simulation.
+func TestTimestamps(t *testing.T) {
+	q, err := New(Event, 32, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	now := int64(0)
+	for i := 0; i < 3000; i++ {
+		x := event{At: now + rand.Int63n(100), Seq: uint32(i)}
+		q.Push(&x)
+		if i%3 == 2 {
+			x, err := q.Pop()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if x.At < now {
+				t.Fatalf("popped %d after %d", x.At, now)
+			}
+			now = x.At
+		}
+	}
+	for q.Len() > 0 {
+		x, _ := q.Pop()
+		if x.At < now {
+			t.Fatalf("popped %d after %d", x.At, now)
+		}
+		now = x.At
+	}
+}
+
+func TestOddSize(t *testing.T) {
+	q, err := New(Rgb, 7, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	for i := 0; i < 100; i++ {
+		x := [3]byte{byte(rand.Intn(256)), byte(i), 0}
+		q.Push(&x)
+	}
+	prev := [3]byte{}
+	for q.Len() > 0 {
+		x, err := q.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Rgb(&x, &prev) < 0 {
+			t.Fatalf("popped %v after %v", x, prev)
+		}
+		prev = x
+	}
+}
+
+func BenchmarkPushPop(b *testing.B) {
+	q, _ := New(Event, 1<<12, b.TempDir())
+	defer q.Close()
+	for i := 0; i < b.N; i++ {
+		x := event{At: rand.Int63(), Seq: uint32(i)}
+		q.Push(&x)
+	}
+	for q.Len() > 0 {
+		q.Pop()
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a Fibonacci heap (a mergeable priority queue with an
+// amortized O(1) decrease-key).
+//
+// The heap is ordered using the same compare function as the slice heaps,
+// a func(*T, *T) int returning a negative number when the first element
+// goes first.
+package heap
+
+import (
+	"math/bits"
+)
+
+// Node is an element pushed onto the heap. It is a handle that stays valid
+// until the element is popped or deleted, also when the heap is melded into
+// another.
+type Node[T any] struct {
+	Elem T
+
+	parent *Node[T]
+	child  *Node[T]
+	left   *Node[T] // siblings in a circular list
+	right  *Node[T]
+	degree int
+	mark   bool
+}
+
+// Heap is a Fibonacci heap. The zero value is not usable, use New.
+type Heap[T any] struct {
+	compar func(*T, *T) int
+	min    *Node[T] // the top root in a circular list of roots
+	n      int
+}
+
+// New returns an empty heap.
+// The compar is a compare function.
+func New[T any](compar func(*T, *T) int) *Heap[T] {
+	return &Heap[T]{compar: compar}
+}
+
+// Len returns the number of elements in the heap.
+func (h *Heap[T]) Len() int {
+	return h.n
+}
+
+// Top returns the top element without removing it, nil if the heap is empty.
+// The complexity is O(1).
+func (h *Heap[T]) Top() *Node[T] {
+	return h.min
+}
+
+// Push pushes the element onto the heap and returns its handle.
+// The complexity is O(1).
+func (h *Heap[T]) Push(elem *T) *Node[T] {
+	x := &Node[T]{Elem: *elem}
+	x.left, x.right = x, x
+	h.root(x)
+	h.n++
+	return x
+}
+
+// Pop removes the top element and returns it, nil if the heap is empty.
+// The complexity is O(log(n)) amortized where n = h.Len().
+func (h *Heap[T]) Pop() *Node[T] {
+	x := h.min
+	if x == nil {
+		return nil
+	}
+
+	// move the children to the root list
+	for c := x.child; c != nil; c = x.child {
+		x.child = c.right
+		if x.child == c {
+			x.child = nil
+		}
+		unlist(c)
+		c.parent, c.mark = nil, false
+		splice(x, c)
+	}
+	x.degree = 0
+
+	if x.right == x {
+		h.min = nil
+	} else {
+		h.min = x.right
+		unlist(x)
+		h.consolidate()
+	}
+	h.n--
+	return x
+}
+
+// Meld moves all the elements of the other heap to this heap. The other heap
+// is left empty, the handles of its elements stay valid in this heap.
+// Both heaps must use the same compare function.
+// The complexity is O(1).
+func (h *Heap[T]) Meld(other *Heap[T]) {
+	if h == other || other.min == nil {
+		return
+	}
+	h.root(other.min)
+	h.n += other.n
+	other.min, other.n = nil, 0
+}
+
+// DecreaseKey sets the element of the node to elem, which must not go after
+// the current element.
+// The complexity is O(1) amortized.
+func (h *Heap[T]) DecreaseKey(x *Node[T], elem *T) {
+	if h.compar(elem, &x.Elem) > 0 {
+		panic("DecreaseKey: the new element goes after the old one")
+	}
+	x.Elem = *elem
+	h.decrease(x, false)
+}
+
+// Delete removes the element of the node from the heap.
+// The complexity is O(log(n)) amortized where n = h.Len().
+func (h *Heap[T]) Delete(x *Node[T]) {
+	h.decrease(x, true)
+	h.Pop()
+}
+
+// decrease moves the node up after its element has decreased, the node goes
+// to the top if top is set.
+func (h *Heap[T]) decrease(x *Node[T], top bool) {
+	p := x.parent
+	if p != nil && (top || h.compar(&x.Elem, &p.Elem) < 0) {
+		h.cut(x, p)
+		for p.parent != nil && p.mark { // cascading cut
+			q := p.parent
+			h.cut(p, q)
+			p = q
+		}
+		if p.parent != nil {
+			p.mark = true
+		}
+	}
+	if top || h.compar(&x.Elem, &h.min.Elem) < 0 {
+		h.min = x
+	}
+}
+
+// cut moves the node x from the children of p to the root list.
+func (h *Heap[T]) cut(x, p *Node[T]) {
+	if p.child == x {
+		p.child = x.right
+		if p.child == x {
+			p.child = nil
+		}
+	}
+	unlist(x)
+	p.degree--
+	x.parent, x.mark = nil, false
+	splice(h.min, x)
+}
+
+// root adds a circular list of detached nodes to the root list.
+func (h *Heap[T]) root(x *Node[T]) {
+	if h.min == nil {
+		h.min = x
+		return
+	}
+	splice(h.min, x)
+	if h.compar(&x.Elem, &h.min.Elem) < 0 {
+		h.min = x
+	}
+}
+
+// consolidate links the roots of an equal degree and finds the new top.
+func (h *Heap[T]) consolidate() {
+	var degrees [2 * bits.UintSize]*Node[T]
+
+	// detach the roots first, the root list changes while linking
+	var roots []*Node[T]
+	for r := h.min; ; {
+		roots = append(roots, r)
+		if r = r.right; r == h.min {
+			break
+		}
+	}
+
+	for _, x := range roots {
+		unlist(x)
+		for d := x.degree; degrees[d] != nil; d = x.degree {
+			y := degrees[d]
+			degrees[d] = nil
+			if h.compar(&y.Elem, &x.Elem) < 0 {
+				x, y = y, x
+			}
+			// y becomes a child of x
+			y.parent, y.mark = x, false
+			if x.child == nil {
+				x.child = y
+			} else {
+				splice(x.child, y)
+			}
+			x.degree++
+		}
+		degrees[x.degree] = x
+	}
+
+	h.min = nil
+	for _, x := range degrees {
+		if x != nil {
+			h.root(x)
+		}
+	}
+}
+
+// unlist removes the node from its circular list, leaving it alone in a list.
+func unlist[T any](x *Node[T]) {
+	x.left.right = x.right
+	x.right.left = x.left
+	x.left, x.right = x, x
+}
+
+// splice joins the circular list of y into the circular list of x.
+func splice[T any](x, y *Node[T]) {
+	xr, yl := x.right, y.left
+	x.right, y.left = y, x
+	yl.right, xr.left = xr, yl
+}
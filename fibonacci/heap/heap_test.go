@@ -0,0 +1,254 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	heap32 "github.com/gomacro/heap/int32/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Int32(a, b *int32) int {
+	return int(*a) - int(*b)
+}
+
+func drain(t *testing.T, h *Heap[int32], want []int32) {
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+	if h.Len() != len(want) {
+		t.Fatalf("Len() = %d; want %d", h.Len(), len(want))
+	}
+	for i := range want {
+		if x := h.Top(); x == nil || x.Elem != want[i] {
+			t.Fatalf("%d.th top got %v; want %d", i, x, want[i])
+		}
+		if x := h.Pop(); x.Elem != want[i] {
+			t.Fatalf("%d.th pop got %d; want %d", i, x.Elem, want[i])
+		}
+	}
+	if h.Pop() != nil || h.Top() != nil || h.Len() != 0 {
+		t.Errorf("heap not empty")
+	}
+}
+
+func TestPushPop(t *testing.T) {
+	h := New(Int32)
+	var want []int32
+	for i := 0; i < 1000; i++ {
+		x := int32(rand.Intn(100))
+		h.Push(&x)
+		want = append(want, x)
+	}
+	drain(t, h, want)
+}
+
+func TestInterleaved(t *testing.T) {
+	h := New(Int32)
+	for i := int32(0); i < 100; i++ {
+		x, y := 2*i+1, 2*i
+		h.Push(&x)
+		h.Push(&y)
+		if x := h.Pop(); x.Elem != i {
+			t.Fatalf("pop got %d; want %d", x.Elem, i)
+		}
+	}
+}
+
+func TestDecreaseKey(t *testing.T) {
+	h := New(Int32)
+	var nodes []*Node[int32]
+	for i := int32(0); i < 500; i++ {
+		x := 1000 + i
+		nodes = append(nodes, h.Push(&x))
+	}
+	h.Pop()
+	nodes = nodes[1:]
+
+	want := make([]int32, len(nodes))
+	for i, x := range nodes {
+		want[i] = x.Elem
+	}
+	for _, i := range rand.Perm(len(nodes))[:200] {
+		y := want[i] - int32(rand.Intn(2000))
+		h.DecreaseKey(nodes[i], &y)
+		want[i] = y
+	}
+	drain(t, h, want)
+}
+
+func TestDecreaseKeyIncrease(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("increased key accepted")
+		}
+	}()
+	h := New(Int32)
+	x, y := int32(1), int32(2)
+	h.DecreaseKey(h.Push(&x), &y)
+}
+
+func TestMeld(t *testing.T) {
+	a, b := New(Int32), New(Int32)
+	var want []int32
+	var nodes []*Node[int32]
+	for i := 0; i < 300; i++ {
+		x := int32(rand.Intn(1000))
+		if i%3 == 0 {
+			nodes = append(nodes, a.Push(&x))
+		} else {
+			nodes = append(nodes, b.Push(&x))
+		}
+		want = append(want, x)
+	}
+	a.Meld(b)
+	if b.Len() != 0 || b.Top() != nil {
+		t.Errorf("melded heap not empty")
+	}
+
+	// the handles stay valid after the meld
+	for i, x := range nodes {
+		y := x.Elem - 1
+		a.DecreaseKey(x, &y)
+		want[i] = y
+	}
+	drain(t, a, want)
+}
+
+func TestDelete(t *testing.T) {
+	h := New(Int32)
+	var nodes []*Node[int32]
+	for i := int32(0); i < 500; i++ {
+		x := int32(rand.Intn(1000))
+		nodes = append(nodes, h.Push(&x))
+	}
+	popped := h.Pop() // consolidate into trees
+
+	var want []int32
+	for i, x := range nodes {
+		switch {
+		case x == popped:
+		case i%3 == 0:
+			h.Delete(x)
+		default:
+			want = append(want, x.Elem)
+		}
+	}
+	drain(t, h, want)
+}
+
+// graph is a synthetic directed graph with random edge weights.
+type graph struct {
+	adj [][]edge
+}
+
+type edge struct {
+	to, w int32
+}
+
+func newGraph(n, degree int) *graph {
+	r := rand.New(rand.NewSource(1))
+	g := &graph{adj: make([][]edge, n)}
+	for v := range g.adj {
+		for i := 0; i < degree; i++ {
+			g.adj[v] = append(g.adj[v], edge{int32(r.Intn(n)), int32(1 + r.Intn(1000))})
+		}
+	}
+	return g
+}
+
+type vertex struct {
+	dist, v int32
+}
+
+func Vertex(a, b *vertex) int {
+	return int(a.dist) - int(b.dist)
+}
+
+func dijkstraFibonacci(g *graph, dist []int32) {
+	nodes := make([]*Node[vertex], len(g.adj))
+	for i := range dist {
+		dist[i] = math.MaxInt32
+	}
+	dist[0] = 0
+
+	h := New(Vertex)
+	nodes[0] = h.Push(&vertex{0, 0})
+	for x := h.Pop(); x != nil; x = h.Pop() {
+		u := x.Elem.v
+		nodes[u] = nil
+		for _, e := range g.adj[u] {
+			d := dist[u] + e.w
+			if d >= dist[e.to] {
+				continue
+			}
+			dist[e.to] = d
+			if nodes[e.to] != nil {
+				h.DecreaseKey(nodes[e.to], &vertex{d, e.to})
+			} else {
+				nodes[e.to] = h.Push(&vertex{d, e.to})
+			}
+		}
+	}
+}
+
+// dijkstraInt32 keeps the vertices in an int32/heap Indirect heap over the
+// distances, the positions are tracked, so a decreased vertex is fixed in
+// place like with DecreaseKey.
+func dijkstraInt32(g *graph, dist []int32) {
+	for i := range dist {
+		dist[i] = math.MaxInt32
+	}
+	dist[0] = 0
+
+	h := heap32.NewIndirect(Int32, dist, true)
+	h.Push(0)
+	for h.Len() > 0 {
+		u := h.Top()
+		h.Remove(0)
+		for _, e := range g.adj[u] {
+			d := dist[u] + e.w
+			if d >= dist[e.to] {
+				continue
+			}
+			dist[e.to] = d
+			if i := h.Position(e.to); i >= 0 {
+				h.Fix(i)
+			} else {
+				h.Push(e.to)
+			}
+		}
+	}
+}
+
+func TestDijkstra(t *testing.T) {
+	g := newGraph(1000, 8)
+	a, b := make([]int32, 1000), make([]int32, 1000)
+	dijkstraFibonacci(g, a)
+	dijkstraInt32(g, b)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("dist[%d] = %d; want %d", i, a[i], b[i])
+		}
+	}
+}
+
+func BenchmarkDijkstraFibonacci(b *testing.B) {
+	g := newGraph(100000, 8)
+	dist := make([]int32, len(g.adj))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dijkstraFibonacci(g, dist)
+	}
+}
+
+func BenchmarkDijkstraInt32(b *testing.B) {
+	g := newGraph(100000, 8)
+	dist := make([]int32, len(g.adj))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dijkstraInt32(g, dist)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// PushAll pushes all the elems onto the heap.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// Small batches are sifted up one by one, large batches re-heapify the
+// subtrees they were appended to.
+// The complexity is O(min(k*log(n), k+log(n)*log(n))) where k = len(elems).
+func PushAll( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap *[]int32, elems []int32) {
+	l := len(*heap)
+	n := l + len(elems)
+
+	*heap = append(*heap, elems...)
+	if n-l <= bits.Len(uint(n)) {
+		for j := l; j < n; j++ {
+			up( /*ts0, */ compar, *heap, j)
+		}
+		return
+	}
+	heapify( /*ts0, */ compar, *heap, l, n, n)
+}
+
+// Merge merges the src heap into the heap. The src is left untouched.
+// The compar is a compare function.
+// The heap and the src are heapified slices.
+// The larger of the two heaps keeps its place, the smaller one is pushed
+// onto it with PushAll.
+// The complexity is O(min(k*log(n), k+log(n)*log(n))) where k is the length
+// of the smaller heap.
+func Merge( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap *[]int32, src []int32) {
+	if len(src) <= len(*heap) {
+		PushAll( /*ts0, */ compar, heap, src)
+		return
+	}
+
+	// rotate the smaller heap behind the larger one
+	tmp := append([]int32(nil), (*heap)...)
+	*heap = append((*heap)[:0], src...)
+	PushAll( /*ts0, */ compar, heap, tmp)
+}
+
+// RemoveAll removes the elements at the indices from the heap in one pass.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// The indices may be unsorted, a duplicate index is removed once.
+// The complexity is O(min(k*log(n)*log(n), n)) where k = len(indices).
+func RemoveAll( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap *[]int32, indices []int) {
+	n := len(*heap)
+	idx := unique(indices)
+	if len(idx) == 0 {
+		return
+	}
+	if idx[0] < 0 || idx[len(idx)-1] >= n {
+		panic("RemoveAll: index out of range")
+	}
+	m := n - len(idx)
+
+	// fill the holes below m with the survivors from the top
+	t, last, holes := len(idx)-1, n-1, 0
+	for _, h := range idx {
+		if h >= m {
+			break
+		}
+		for t >= 0 && idx[t] == last {
+			t--
+			last--
+		}
+		(*heap)[h] = (*heap)[last]
+		last--
+		holes++
+	}
+	(*heap) = (*heap)[:m]
+
+	if holes*bits.Len(uint(m)) >= m/2 {
+		heapify( /*ts0, */ compar, *heap, 0, m, m)
+		return
+	}
+	// re-heapify the paths from the holes to the root, bottom up
+	var path []int
+	for _, h := range idx[:holes] {
+		for ; h > 0; h = (h - 1) / 2 {
+			path = append(path, h)
+		}
+	}
+	path = append(path, 0)
+	sort.Sort(sort.Reverse(sort.IntSlice(path)))
+	for i, j := range path {
+		if i == 0 || path[i-1] != j {
+			down( /*ts0, */ compar, *heap, j, m)
+		}
+	}
+}
+
+// heapify re-establishes the heap ordering after the elements at the
+// indices lo..hi-1 have changed.
+func heapify( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap []int32, lo, hi, n int) {
+	for {
+		for i := hi - 1; i >= lo; i-- {
+			down( /*ts0, */ compar, heap, i, n)
+		}
+		if lo == 0 {
+			break
+		}
+		lo, hi = (lo-1)/2, min(lo, hi/2)
+	}
+}
+
+func unique(indices []int) []int {
+	idx := make([]int, len(indices))
+	copy(idx, indices)
+	sort.Ints(idx)
+	k := 0
+	for i := range idx {
+		if i == 0 || idx[i] != idx[k-1] {
+			idx[k] = idx[i]
+			k++
+		}
+	}
+	return idx[:k]
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPushAll(t *testing.T) {
+	for _, k := range []int{0, 1, 3, 10, 100, 1000} {
+		h := []int32{}
+		for i := 0; i < 100; i++ {
+			x := int32(rand.Intn(1000))
+			Push(Int32, &h, &x)
+		}
+		elems := make([]int32, k)
+		for i := range elems {
+			elems[i] = int32(rand.Intn(1000))
+		}
+		PushAll(Int32, &h, elems)
+		myHeap(h).verify(t, 0)
+		if len(h) != 100+k {
+			t.Errorf("len(h) = %d; want %d", len(h), 100+k)
+		}
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	for _, k := range []int{0, 1, 2, 5, 50, 199, 200} {
+		h := []int32{}
+		for i := int32(0); i < 200; i++ {
+			Push(Int32, &h, &i)
+		}
+		indices := rand.Perm(len(h))[:k]
+		indices = append(indices, indices...) // duplicates are removed once
+
+		m := make(map[int32]bool)
+		for _, i := range indices {
+			m[h[i]] = true
+		}
+		RemoveAll(Int32, &h, indices)
+		myHeap(h).verify(t, 0)
+
+		if len(h) != 200-k {
+			t.Fatalf("len(h) = %d; want %d", len(h), 200-k)
+		}
+		seen := make(map[int32]bool)
+		for _, x := range h {
+			if m[x] || seen[x] {
+				t.Errorf("%d was not removed or is duplicated", x)
+			}
+			seen[x] = true
+		}
+	}
+}
+
+func BenchmarkPushAll(b *testing.B) {
+	const n = 10000
+	elems := make([]int32, n)
+	for i := range elems {
+		elems[i] = int32(rand.Intn(n))
+	}
+	h := make([]int32, 0, n)
+	for i := 0; i < b.N; i++ {
+		h = h[:0]
+		PushAll(Int32, &h, elems)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	for _, sizes := range [][2]int{{0, 0}, {0, 10}, {10, 0}, {100, 3}, {3, 100}, {50, 60}} {
+		h, src := []int32{}, []int32{}
+		for i := 0; i < sizes[0]; i++ {
+			x := int32(rand.Intn(1000))
+			Push(Int32, &h, &x)
+		}
+		for i := 0; i < sizes[1]; i++ {
+			x := int32(rand.Intn(1000))
+			Push(Int32, &src, &x)
+		}
+		Merge(Int32, &h, src)
+		myHeap(h).verify(t, 0)
+		if len(h) != sizes[0]+sizes[1] {
+			t.Errorf("len(h) = %d; want %d", len(h), sizes[0]+sizes[1])
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+// Heap is a heap in the Binary layout, it can be encoded with
+// encoding.BinaryMarshaler.
+// The Compar is a compare function.
+// The Slice is a heapified slice.
+// The BottomUp selects the bottom-up sift-down for Remove and Heapify, see
+// RemoveBottomUp. It is not encoded, UnmarshalBinary heapifies an unordered
+// encoding with the BottomUp of the receiver.
+type Heap struct {
+	Compar   func(*int32, *int32) int
+	Slice    []int32
+	BottomUp bool
+}
+
+// Len returns the number of the elements.
+func (h *Heap) Len() int { return len(h.Slice) }
+
+// Index returns the slot of the i-th element, it is i.
+func (h *Heap) Index(i int) int { return i }
+
+// Parent returns the index of the parent of the element at index i > 0.
+func (h *Heap) Parent(i int) int { return (i - 1) / 2 }
+
+// Push pushes the element x onto the heap, see Push.
+func (h *Heap) Push(elem *int32) { Push(h.Compar, &h.Slice, elem) }
+
+// Remove removes the element at index i from the heap, see Remove and
+// RemoveBottomUp.
+func (h *Heap) Remove(i int) {
+	if h.BottomUp {
+		RemoveBottomUp(h.Compar, &h.Slice, i)
+		return
+	}
+	Remove(h.Compar, &h.Slice, i)
+}
+
+// Fix re-establishes the heap ordering after the element at index i has
+// changed its value, see Fix.
+func (h *Heap) Fix(i int) { Fix(h.Compar, h.Slice, i) }
+
+// Heapify initializes the heap in place, see Heapify and HeapifyBottomUp.
+func (h *Heap) Heapify() {
+	if len(h.Slice) == 0 {
+		return
+	}
+	if h.BottomUp {
+		HeapifyBottomUp(h.Compar, h.Slice, h.Slice)
+		return
+	}
+	Heapify(h.Compar, h.Slice, h.Slice)
+}
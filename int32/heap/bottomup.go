@@ -0,0 +1,73 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+// RemoveBottomUp removes the element at index i from the heap like Remove,
+// but sifts down bottom-up. It takes about half the compares of Remove, it
+// pays off when the compare function is expensive.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// The complexity is O(log(n)) where n = h.Len().
+func RemoveBottomUp( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap *[]int32, i int) {
+	n := len(*heap) - 1
+	if n != i {
+		(*heap)[i] = (*heap)[n]
+		downBottomUp( /*ts0, */ compar, (*heap), i, n)
+		if i != 0 {
+			up( /*ts0, */ compar, (*heap), i)
+		}
+	}
+	(*heap) = (*heap)[:n]
+}
+
+// HeapifyBottomUp initializes the heap like Heapify, but sifts down
+// bottom-up.
+// The compar is a compare function.
+// Then heap is a source slice. Dst is a result slice. In place is supported.
+// Its complexity is O(n) where n = h.Len().
+func HeapifyBottomUp( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, dst []int32, heap []int32) {
+	n := len(heap)
+	if &dst[0] == &heap[0] {
+		for i := n/2 - 1; i >= 0; i-- {
+			downBottomUp( /*ts0, */ compar, heap, i, n)
+		}
+	} else {
+		// FIXME: out of place heapify not implemented
+		panic("FIXME: out of place heapify not implemented")
+	}
+}
+
+// downBottomUp is the bottom-up (Floyd) sift-down. It descends along the
+// smaller children to a leaf with one compare per level, then climbs back to
+// the slot of the element. The element usually belongs near a leaf.
+func downBottomUp( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap []int32, i, n int) {
+	k := i
+	for {
+		j1 := 2*k + 1
+		if j1 >= n || j1 < 0 { // j1 < 0 after int32 overflow
+			break
+		}
+		j := j1 // left child
+		if j2 := j1 + 1; j2 < n && compar(&heap[j1], &heap[j2]) >= 0 {
+			j = j2 // = 2*k + 2  // right child
+		}
+		k = j
+	}
+	for k != i && compar(&heap[i], &heap[k]) < 0 {
+		k = (k - 1) / 2
+	}
+	if k == i {
+		return
+	}
+	{ // shift
+		x := heap[k]
+		for p := k; p != i; {
+			j := (p - 1) / 2
+			heap[j], x = x, heap[j]
+			p = j
+		}
+		heap[k] = x
+	}
+}
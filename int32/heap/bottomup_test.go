@@ -0,0 +1,76 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestBottomUp(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 10, 100, 1000} {
+		h := make([]int32, n)
+		for i := range h {
+			h[i] = rand.Int31n(100)
+		}
+		HeapifyBottomUp(Int32, h, h)
+		myHeap(h).verify(t, 0)
+
+		for len(h) > n/2 {
+			RemoveBottomUp(Int32, &h, rand.Intn(len(h)))
+			myHeap(h).verify(t, 0)
+		}
+		for last := int32(-1); len(h) > 0; {
+			if h[0] < last {
+				t.Fatalf("%d popped after %d", h[0], last)
+			}
+			last = h[0]
+			RemoveBottomUp(Int32, &h, 0)
+		}
+	}
+}
+
+func TestHeapBottomUp(t *testing.T) {
+	h := &Heap{Compar: Int32, BottomUp: true}
+	for i := 0; i < 100; i++ {
+		h.Slice = append(h.Slice, rand.Int31())
+	}
+	h.Heapify()
+	myHeap(h.Slice).verify(t, 0)
+	for h.Len() > 0 {
+		h.Remove(rand.Intn(h.Len()))
+		myHeap(h.Slice).verify(t, 0)
+	}
+}
+
+// TestUnmarshalBottomUp heapifies an unordered encoding with the BottomUp of
+// the receiver, the bottom-up heapify is told by its fewer compares.
+func TestUnmarshalBottomUp(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	s := make([]int32, 1000)
+	for i := range s {
+		s[i] = r.Int31()
+	}
+	var b bytes.Buffer
+	if err := Encode(&b, s, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var compares [2]int
+	for i, bottomUp := range []bool{false, true} {
+		h := &Heap{Compar: func(a, b *int32) int {
+			compares[i]++
+			return Int32(a, b)
+		}, BottomUp: bottomUp}
+		if err := h.UnmarshalBinary(b.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		myHeap(h.Slice).verify(t, 0)
+	}
+	if compares[1] >= compares[0] {
+		t.Errorf("bottom-up compares %d, top-down %d", compares[1], compares[0])
+	}
+}
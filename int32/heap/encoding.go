@@ -0,0 +1,143 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// The encoding is the one of the unsafe heap with a 4 byte element size and
+// the 32-bit width backend, the two packages read each other's encodings.
+//
+//	magic   [4]byte "GMHP"
+//	version uint8   1
+//	width   uint8   32
+//	flags   uint8   1 if the elements are heap-ordered
+//	_       uint8
+//	size    uint32  4
+//	count   uint64  number of the elements
+const (
+	magic      = "GMHP"
+	version    = 1
+	width      = 32
+	headerSize = 20
+	chunk      = 1 << 18 // elements decoded at once
+)
+
+// ErrFormat is returned when decoding data that is not an encoded heap.
+var ErrFormat = errors.New("heap: invalid encoding")
+
+// Encode writes the heap to w.
+// The heap is a slice.
+// The ordered tells if the heap is heapified, Decode then skips Heapify.
+func Encode(w io.Writer, heap []int32, ordered bool) error {
+	var b [headerSize]byte
+	copy(b[:], magic)
+	b[4] = version
+	b[5] = width
+	if ordered {
+		b[6] = 1
+	}
+	binary.LittleEndian.PutUint32(b[8:], 4)
+	binary.LittleEndian.PutUint64(b[12:], uint64(len(heap)))
+	if _, err := w.Write(b[:]); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, heap)
+}
+
+// Decode reads a heap encoded by Encode. The elements replace the contents
+// of the heap, the heap is heapified unless the encoding is heap-ordered.
+// The compar is a compare function, it may be nil for a heap-ordered encoding.
+// The heap is a pointer to a slice.
+func Decode(r io.Reader, compar func(*int32, *int32) int, heap *[]int32) error {
+	return decode(r, compar, heap, Heapify)
+}
+
+// decode is Decode with the heapify of an unordered encoding.
+func decode(r io.Reader, compar func(*int32, *int32) int, heap *[]int32, heapify func(func(*int32, *int32) int, []int32, []int32)) error {
+	var b [headerSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if string(b[:4]) != magic || b[4] != version || b[5] != width || b[6] > 1 {
+		return ErrFormat
+	}
+	ordered := b[6] == 1
+	if size := binary.LittleEndian.Uint32(b[8:]); size != 4 {
+		return fmt.Errorf("heap: element size %d, want 4", size)
+	}
+	n := binary.LittleEndian.Uint64(b[12:])
+	if n > uint64(^uint(0)>>3) {
+		return ErrFormat
+	}
+	if !ordered && compar == nil {
+		return errors.New("heap: decoding an unordered heap without compar")
+	}
+
+	// the count is not trusted, the slice grows with the data actually read
+	*heap = make([]int32, 0, min(n, chunk))
+	for i := uint64(0); i < n; i += chunk {
+		m := int(min(n-i, chunk))
+		*heap = slices.Grow(*heap, m)[:int(i)+m]
+		if err := binary.Read(r, binary.LittleEndian, (*heap)[i:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = ErrFormat // a truncated payload
+			}
+			return err
+		}
+	}
+	if !ordered && n > 0 {
+		heapify( /*ts0, */ compar, *heap, *heap)
+	}
+	return nil
+}
+
+// MarshalBinary encodes the heap, the encoding is heap-ordered.
+func (h *Heap) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	err := Encode(&b, h.Slice, true)
+	return b.Bytes(), err
+}
+
+// UnmarshalBinary decodes the heap into the Slice, see Heap.BottomUp.
+func (h *Heap) UnmarshalBinary(data []byte) error {
+	heapify := Heapify
+	if h.BottomUp {
+		heapify = HeapifyBottomUp
+	}
+	return decode(bytes.NewReader(data), h.Compar, &h.Slice, heapify)
+}
+
+// MarshalBinary encodes the heap in the fill order. The tree of the Paged
+// layout is not the binary one, so the encoding is not heap-ordered and the
+// Decode heapifies it.
+func (h *PagedHeap) MarshalBinary() ([]byte, error) {
+	s := make([]int32, h.n)
+	for i := range s {
+		s[i] = h.Slice[h.Index(i)]
+	}
+	var b bytes.Buffer
+	err := Encode(&b, s, false)
+	return b.Bytes(), err
+}
+
+// UnmarshalBinary decodes the heap into the Slice.
+func (h *PagedHeap) UnmarshalBinary(data []byte) error {
+	var s []int32
+	if err := Decode(bytes.NewReader(data), h.Compar, &s); err != nil {
+		return err
+	}
+	*h = *New(h.Compar, s, Paged).(*PagedHeap)
+	return nil
+}
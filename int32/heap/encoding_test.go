@@ -0,0 +1,97 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	h := []int32{}
+	for i := int32(100); i > 0; i-- {
+		Push( /*&NULL, */ Int32, &h, &i)
+	}
+
+	var b bytes.Buffer
+	if err := Encode(&b, h, true); err != nil {
+		t.Fatal(err)
+	}
+	var g []int32
+	if err := Decode(bytes.NewReader(b.Bytes()), nil, &g); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, h) {
+		t.Errorf("Has %v", g)
+	}
+}
+
+func TestEncodeUnordered(t *testing.T) {
+	h := []int32{5, 3, 8, -1, 9, 0, 2}
+
+	var b bytes.Buffer
+	if err := Encode(&b, h, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := Decode(bytes.NewReader(b.Bytes()), nil, &h); err == nil {
+		t.Errorf("unordered heap decoded without compar")
+	}
+	if err := Decode(bytes.NewReader(b.Bytes()), Int32, &h); err != nil {
+		t.Fatal(err)
+	}
+	myHeap(h).verify(t, 0)
+	if h[0] != -1 || len(h) != 7 {
+		t.Errorf("Has %v", h)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	var b bytes.Buffer
+	Encode(&b, []int32{1, 2, 3}, true)
+	data := b.Bytes()
+
+	var h []int32
+	for n := 0; n < len(data); n++ {
+		err := Decode(bytes.NewReader(data[:n]), Int32, &h)
+		want := ErrFormat // a truncated payload
+		if n < headerSize {
+			want = io.ErrUnexpectedEOF
+		}
+		if err != want {
+			t.Errorf("Decode of %d bytes: %v", n, err)
+		}
+	}
+	huge := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint64(huge[12:], 1<<44)
+	if err := Decode(bytes.NewReader(huge), Int32, &h); err != ErrFormat {
+		t.Errorf("Decode of a huge count: %v", err)
+	}
+
+	bad := append([]byte("XMHP"), data[4:]...)
+	if err := Decode(bytes.NewReader(bad), Int32, &h); err != ErrFormat {
+		t.Errorf("Decode of a bad magic: %v", err)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	var m encoding.BinaryMarshaler = &Heap{Compar: Int32, Slice: []int32{1, 2, 3, 4}}
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Heap{Compar: Int32}
+	var u encoding.BinaryUnmarshaler = g
+	if err := u.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g.Slice, []int32{1, 2, 3, 4}) {
+		t.Errorf("Has %v", g.Slice)
+	}
+}
@@ -47,20 +47,34 @@ func Remove( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap *[]int3
 // The compar is a compare function.
 // The heap is a heapified slice.
 func Another( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap []int32) {
-	// first we check that [1] < [2]
-
-	if len(heap) <= 2 || compar(&heap[1], &heap[2]) <= 0 {
-		// ok
-		return
-	}
+	Prefix( /*ts0, */ compar, heap, 2)
+}
 
-	{ // swap
-		x := heap[1]
-		heap[1] = heap[2]
-		heap[2] = x
+// Prefix loads the k top values to heap[0..k-1] in the sorted order.
+// The heap stays heapified, a sorted prefix of a heap is heap-ordered.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// The complexity is O(k*k + k*log(n)) where n = h.Len().
+func Prefix( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap []int32, k int) {
+	n := len(heap)
+	for i := 1; i < k && i < n; i++ {
+		// the candidates for heap[i] are the children of the prefix
+		c := i
+		for j := i + 1; j <= 2*i && j < n; j++ {
+			if compar(&heap[j], &heap[c]) < 0 {
+				c = j
+			}
+		}
+		if c == i {
+			continue
+		}
+		{ // swap
+			x := heap[i]
+			heap[i] = heap[c]
+			heap[c] = x
+		}
+		down( /*ts0, */ compar, heap, c, n)
 	}
-
-	down( /*ts0, */ compar, heap, 2, len(heap)) //FIXME: shouldn't be len(heap)-1?
 }
 
 // Fix re-establishes the heap ordering after the element at index i has
@@ -71,7 +85,7 @@ func Another( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap []int3
 // The heap is a slice.
 // The complexity is O(log(n)) where n = h.Len().
 func Fix( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap []int32, i int) {
-	down( /*ts0, */ compar, heap, i, len(heap)) //FIXME: shouldn't be len(heap)-1?
+	down( /*ts0, */ compar, heap, i, len(heap)) // n is a length, not the last index
 	up( /*ts0, */ compar, heap, i)
 }
 
@@ -93,39 +107,57 @@ func Heapify( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, dst []int32
 	}
 }
 
+// up and down keep the element in place while its slot is searched, then
+// shift the path into the hole and write the element once.
 func up( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap []int32, j int) {
-	for {
-		i := (j - 1) / 2 // parent
-		if i == j || compar(&heap[j], &heap[i]) >= 0 {
+	k := j
+	for k > 0 {
+		i := (k - 1) / 2 // parent
+		if compar(&heap[j], &heap[i]) >= 0 {
 			break
 		}
-		{ // swap
-			x := heap[i]
-			heap[i] = heap[j]
-			heap[j] = x
+		k = i
+	}
+	if k == j {
+		return
+	}
+	{ // shift
+		x := heap[j]
+		for p := j; p != k; {
+			i := (p - 1) / 2
+			heap[p] = heap[i]
+			p = i
 		}
-		j = i
+		heap[k] = x
 	}
 }
 
 func down( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap []int32, i, n int) {
+	k := i
 	for {
-		j1 := 2*i + 1
+		j1 := 2*k + 1
 		if j1 >= n || j1 < 0 { // j1 < 0 after int32 overflow
 			break
 		}
 		j := j1 // left child
 		if j2 := j1 + 1; j2 < n && compar(&heap[j1], &heap[j2]) >= 0 {
-			j = j2 // = 2*i + 2  // right child
+			j = j2 // = 2*k + 2  // right child
 		}
 		if compar(&heap[j], &heap[i]) >= 0 {
 			break
 		}
-		{ // swap
-			x := heap[i]
-			heap[i] = heap[j]
-			heap[j] = x
+		k = j
+	}
+	if k == i {
+		return
+	}
+	{ // shift
+		x := heap[k]
+		for p := k; p != i; {
+			j := (p - 1) / 2
+			heap[j], x = x, heap[j]
+			p = j
 		}
-		i = j
+		heap[k] = x
 	}
 }
@@ -6,6 +6,7 @@ package heap
 
 import (
 	"math/rand"
+	"sort"
 	"testing"
 )
 
@@ -261,3 +262,37 @@ func TestAnother0(t *testing.T) {
 	myHeap(h).verify(t, 0)
 
 }
+
+// down is given the length of the heap, not the last index: the element at
+// the last index must take part in the sift.
+func TestAnother1(t *testing.T) {
+	h := []int32{0, 5, 3, 6, 7, 4}
+	myHeap(h).verify(t, 0)
+
+	Another( /*&NULL, */ Int32, h)
+
+	if h[1] != 3 || h[5] != 5 {
+		t.Errorf("Has %v", h)
+	}
+	myHeap(h).verify(t, 0)
+}
+
+func TestPrefix(t *testing.T) {
+	for _, k := range []int{0, 1, 2, 3, 10, 99, 100, 200} {
+		h := []int32{}
+		for i := 0; i < 100; i++ {
+			x := int32(rand.Intn(50))
+			Push( /*&NULL, */ Int32, &h, &x)
+		}
+		want := append([]int32(nil), h...)
+		sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+
+		Prefix( /*&NULL, */ Int32, h, k)
+		myHeap(h).verify(t, 0)
+		for i := 0; i < k && i < len(h); i++ {
+			if h[i] != want[i] {
+				t.Fatalf("Prefix(%d): h[%d] = %d; want %d", k, i, h[i], want[i])
+			}
+		}
+	}
+}
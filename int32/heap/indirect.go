@@ -0,0 +1,165 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+// Indirect is a heap of the indices into a caller-owned slice, the elements
+// themselves are never moved.
+// The Compar is a compare function, it receives the referenced elements.
+// The Data is the caller-owned slice, it may be appended to between the heap
+// operations.
+// The Slice is a heap of the indices into the Data.
+// The Pos is an optional inverse of the Slice, Pos[d] is the position of the
+// index d in the Slice or -1. It is maintained when it is not nil.
+type Indirect struct {
+	Compar func(*int32, *int32) int
+	Data   []int32
+	Slice  []int32
+	Pos    []int32
+}
+
+// NewIndirect creates an empty indirect heap over the data.
+// The track allocates the Pos for the Position lookups.
+func NewIndirect(compar func(*int32, *int32) int, data []int32, track bool) *Indirect {
+	h := &Indirect{Compar: compar, Data: data}
+	if track {
+		h.Pos = make([]int32, len(data))
+		for i := range h.Pos {
+			h.Pos[i] = -1
+		}
+	}
+	return h
+}
+
+// Len returns the number of the indices in the heap.
+func (h *Indirect) Len() int { return len(h.Slice) }
+
+// Top returns the index of the top element.
+func (h *Indirect) Top() int32 { return h.Slice[0] }
+
+// Position returns the position of the index d in the Slice, or -1 when the
+// d is not in the heap. It requires the Pos.
+// The complexity is O(1).
+func (h *Indirect) Position(d int32) int {
+	if d < 0 || int(d) >= len(h.Pos) {
+		return -1
+	}
+	return int(h.Pos[d])
+}
+
+// Push pushes the index d of an element of the Data onto the heap.
+// It panics when the d is out of range, or when the d is in the heap already
+// and the Pos is maintained. Without the Pos the caller must not push the d
+// twice.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Indirect) Push(d int32) {
+	if d < 0 || int(d) >= len(h.Data) {
+		panic("Push: index out of range")
+	}
+	if h.Pos != nil {
+		for len(h.Pos) <= int(d) {
+			h.Pos = append(h.Pos, -1)
+		}
+		if h.Pos[d] >= 0 {
+			panic("Push: index already in the heap")
+		}
+	}
+	h.Slice = append(h.Slice, d)
+	h.set(len(h.Slice)-1, d)
+	h.up(len(h.Slice) - 1)
+}
+
+// Remove removes the index at the position i from the heap.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Indirect) Remove(i int) {
+	n := len(h.Slice) - 1
+	if h.Pos != nil {
+		h.Pos[h.Slice[i]] = -1
+	}
+	if n != i {
+		h.set(i, h.Slice[n])
+		h.down(i, n)
+		if i != 0 {
+			h.up(i)
+		}
+	}
+	h.Slice = h.Slice[:n]
+}
+
+// Fix re-establishes the heap ordering after the element referenced from the
+// position i has changed its value.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Indirect) Fix(i int) {
+	h.down(i, len(h.Slice))
+	h.up(i)
+}
+
+// Heapify re-establishes the heap ordering of the Slice, and rebuilds the Pos.
+// It panics when an index is out of range.
+// Its complexity is O(n) where n = h.Len().
+func (h *Indirect) Heapify() {
+	n := len(h.Slice)
+	for _, d := range h.Slice {
+		if d < 0 || int(d) >= len(h.Data) {
+			panic("Heapify: index out of range")
+		}
+	}
+	for h.Pos != nil && len(h.Pos) < len(h.Data) {
+		h.Pos = append(h.Pos, -1)
+	}
+	for i := n/2 - 1; i >= 0; i-- {
+		h.down(i, n)
+	}
+	if h.Pos != nil {
+		for i := range h.Pos {
+			h.Pos[i] = -1
+		}
+		for i, d := range h.Slice {
+			h.set(i, d)
+		}
+	}
+}
+
+// set stores the index d at the position i.
+func (h *Indirect) set(i int, d int32) {
+	h.Slice[i] = d
+	if h.Pos != nil {
+		h.Pos[d] = int32(i)
+	}
+}
+
+func (h *Indirect) up(j int) {
+	compar, data, heap := h.Compar, h.Data, h.Slice
+	d := heap[j]
+	for j > 0 {
+		i := (j - 1) / 2 // parent
+		if compar(&data[d], &data[heap[i]]) >= 0 {
+			break
+		}
+		h.set(j, heap[i])
+		j = i
+	}
+	h.set(j, d)
+}
+
+func (h *Indirect) down(i, n int) {
+	compar, data, heap := h.Compar, h.Data, h.Slice
+	d := heap[i]
+	for {
+		j1 := 2*i + 1
+		if j1 >= n || j1 < 0 { // j1 < 0 after int32 overflow
+			break
+		}
+		j := j1 // left child
+		if j2 := j1 + 1; j2 < n && compar(&data[heap[j1]], &data[heap[j2]]) >= 0 {
+			j = j2 // = 2*i + 2  // right child
+		}
+		if compar(&data[heap[j]], &data[d]) >= 0 {
+			break
+		}
+		h.set(i, heap[j])
+		i = j
+	}
+	h.set(i, d)
+}
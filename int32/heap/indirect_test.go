@@ -0,0 +1,131 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func verifyIndirect(t *testing.T, h *Indirect) {
+	for i := 1; i < h.Len(); i++ {
+		if Int32(&h.Data[h.Slice[i]], &h.Data[h.Slice[(i-1)/2]]) < 0 {
+			t.Fatalf("heap invariant invalidated at %d", i)
+		}
+	}
+	for i, d := range h.Slice {
+		if h.Position(d) != i {
+			t.Fatalf("position of %d is %d, want %d", d, h.Position(d), i)
+		}
+	}
+}
+
+func TestIndirect(t *testing.T) {
+	data := make([]int32, 1000)
+	for i := range data {
+		data[i] = rand.Int31n(100)
+	}
+	h := NewIndirect(Int32, data, true)
+	for d := range data {
+		h.Push(int32(d))
+	}
+	verifyIndirect(t, h)
+
+	for i := 0; i < 500; i++ {
+		d := int32(rand.Intn(len(data)))
+		if p := h.Position(d); p >= 0 {
+			if i&1 == 0 {
+				h.Data[d] = rand.Int31n(100)
+				h.Fix(p)
+			} else {
+				h.Remove(p)
+			}
+		} else {
+			h.Push(d)
+		}
+		verifyIndirect(t, h)
+	}
+
+	// the data grows, the pos follows
+	h.Data = append(h.Data, -1)
+	h.Push(int32(len(h.Data) - 1))
+	if h.Top() != int32(len(h.Data)-1) {
+		t.Errorf("top is %d, want %d", h.Top(), len(h.Data)-1)
+	}
+
+	last := int32(-1)
+	for h.Len() > 0 {
+		d := h.Top()
+		if h.Data[d] < last {
+			t.Fatalf("%d popped after %d", h.Data[d], last)
+		}
+		last = h.Data[d]
+		h.Remove(0)
+		if h.Position(d) != -1 {
+			t.Fatalf("removed %d has position %d", d, h.Position(d))
+		}
+	}
+}
+
+func TestIndirectHeapify(t *testing.T) {
+	data := []int32{5, 3, 8, 1, 9, 2}
+	h := NewIndirect(Int32, data, true)
+	h.Slice = []int32{0, 1, 2, 3, 4, 5}
+	h.Heapify()
+	verifyIndirect(t, h)
+	if h.Top() != 3 {
+		t.Errorf("top is %d, want 3", h.Top())
+	}
+	for i, x := range []int32{5, 3, 8, 1, 9, 2} {
+		if data[i] != x {
+			t.Fatalf("data moved: %v", data)
+		}
+	}
+}
+
+func TestIndirectRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("index out of the data accepted")
+		}
+	}()
+	NewIndirect(Int32, make([]int32, 3), false).Push(3)
+}
+
+func TestIndirectTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("index pushed twice")
+		}
+	}()
+	h := NewIndirect(Int32, make([]int32, 3), true)
+	h.Push(1)
+	h.Push(1)
+}
+
+func TestIndirectHeapifyRange(t *testing.T) {
+	for _, d := range []int32{-1, 3} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("index %d heapified", d)
+				}
+			}()
+			h := NewIndirect(Int32, make([]int32, 3), true)
+			h.Slice = []int32{0, d}
+			h.Heapify()
+		}()
+	}
+
+	// the Data grew after the Pos was allocated
+	h := NewIndirect(Int32, []int32{3, 2}, true)
+	h.Data = append(h.Data, 1)
+	h.Slice = []int32{0, 1, 2}
+	h.Heapify()
+	verifyIndirect(t, h)
+	if h.Position(-1) != -1 || h.Position(3) != -1 {
+		t.Errorf("positions of the indices out of range: %d, %d", h.Position(-1), h.Position(3))
+	}
+}
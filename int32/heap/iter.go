@@ -0,0 +1,52 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"iter"
+)
+
+// Ordered returns an iterator over the elements of the heap in the priority
+// order. The heap is not modified, it must not be modified while iterating.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// The complexity is O(k*log(k)) for the first k elements.
+func Ordered( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap []int32) iter.Seq[int32] {
+	return func(yield func(int32) bool) {
+		if len(heap) == 0 {
+			return
+		}
+		// the frontier holds the next candidates keyed by their indices
+		frontier := &KV[int]{Compar: compar}
+		frontier.Push(heap[0], 0)
+		for frontier.Len() > 0 {
+			x, i := frontier.Keys[0], frontier.Values[0]
+			frontier.Remove(0)
+			if !yield(x) {
+				return
+			}
+			for j := 2*i + 1; j <= 2*i+2 && j < len(heap); j++ {
+				frontier.Push(heap[j], j)
+			}
+		}
+	}
+}
+
+// Drain returns an iterator that removes the top element of the heap and
+// yields it until the heap is empty or the iteration stops.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// The complexity is O(log(n)) per element where n = h.Len().
+func Drain( /*ts0 *[1]uintptr, */ compar func(*int32, *int32) int, heap *[]int32) iter.Seq[int32] {
+	return func(yield func(int32) bool) {
+		for len(*heap) > 0 {
+			x := (*heap)[0]
+			Remove( /*ts0, */ compar, heap, 0)
+			if !yield(x) {
+				return
+			}
+		}
+	}
+}
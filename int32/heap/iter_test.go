@@ -0,0 +1,67 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestOrdered(t *testing.T) {
+	h := []int32{}
+	for i := 0; i < 100; i++ {
+		x := int32(rand.Intn(50))
+		Push(Int32, &h, &x)
+	}
+	saved := append([]int32(nil), h...)
+
+	var got []int32
+	for x := range Ordered(Int32, h) {
+		got = append(got, x)
+	}
+	for i := range h {
+		if h[i] != saved[i] {
+			t.Fatalf("the heap was modified")
+		}
+	}
+
+	for i := 0; len(h) > 0; i++ {
+		if got[i] != h[0] {
+			t.Fatalf("%d.th got %d; want %d", i, got[i], h[0])
+		}
+		Remove(Int32, &h, 0)
+	}
+
+	n := 0
+	for range Ordered(Int32, saved) {
+		if n++; n == 5 {
+			break
+		}
+	}
+	for range Ordered(Int32, nil) {
+		t.Errorf("empty heap yields")
+	}
+}
+
+func TestDrain(t *testing.T) {
+	h := []int32{}
+	for i := int32(10); i > 0; i-- {
+		Push(Int32, &h, &i)
+	}
+
+	want := int32(1)
+	for x := range Drain(Int32, &h) {
+		if x != want {
+			t.Errorf("got %d; want %d", x, want)
+		}
+		myHeap(h).verify(t, 0)
+		if want++; want == 6 {
+			break
+		}
+	}
+	if len(h) != 5 || h[0] != 6 {
+		t.Errorf("Has %v", h)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/bits"
+)
+
+// KV is a heap of int32 keys with a value moved alongside every key, so that
+// ids need not be packed into the low bits of the keys by hand.
+// The Compar is a compare function of the keys.
+// The Keys is a heapified slice, the Values[i] belongs to the Keys[i].
+// The top is Keys[0], Values[0]; pop it with Remove(0).
+type KV[V any] struct {
+	Compar func(*int32, *int32) int
+	Keys   []int32
+	Values []V
+}
+
+// Len returns the number of the keys.
+func (h *KV[V]) Len() int { return len(h.Keys) }
+
+// Push pushes the key with the value onto the heap.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *KV[V]) Push(key int32, value V) {
+	h.Keys = append(h.Keys, key)
+	h.Values = append(h.Values, value)
+	h.up(len(h.Keys) - 1)
+}
+
+// Remove removes the key and the value at index i from the heap.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *KV[V]) Remove(i int) {
+	n := len(h.Keys) - 1
+	if n != i {
+		h.Keys[i], h.Values[i] = h.Keys[n], h.Values[n]
+		h.down(i, n)
+		if i != 0 {
+			h.up(i)
+		}
+	}
+	var zero V
+	h.Values[n] = zero // drop the reference
+	h.Keys, h.Values = h.Keys[:n], h.Values[:n]
+}
+
+// Fix re-establishes the heap ordering after the key at index i has changed
+// its value.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *KV[V]) Fix(i int) {
+	h.down(i, len(h.Keys))
+	h.up(i)
+}
+
+// Heapify establishes the heap ordering of the Keys and the Values.
+// Its complexity is O(n) where n = h.Len().
+func (h *KV[V]) Heapify() {
+	if len(h.Keys) != len(h.Values) {
+		panic("Heapify: the keys and the values differ in length")
+	}
+	n := len(h.Keys)
+	for i := n/2 - 1; i >= 0; i-- {
+		h.down(i, n)
+	}
+}
+
+// up and down keep the key in place while its slot is searched, then shift
+// the path into the hole like the int32 heap does.
+func (h *KV[V]) up(j int) {
+	compar, keys, values := h.Compar, h.Keys, h.Values
+	k := j
+	for k > 0 {
+		i := (k - 1) / 2 // parent
+		if compar(&keys[j], &keys[i]) >= 0 {
+			break
+		}
+		k = i
+	}
+	if k == j {
+		return
+	}
+	x, v := keys[j], values[j]
+	for p := j; p != k; {
+		i := (p - 1) / 2
+		keys[p], values[p] = keys[i], values[i]
+		p = i
+	}
+	keys[k], values[k] = x, v
+}
+
+func (h *KV[V]) down(i, n int) {
+	compar, keys, values := h.Compar, h.Keys, h.Values
+	k := i
+	for {
+		j1 := 2*k + 1
+		if j1 >= n || j1 < 0 { // j1 < 0 after int32 overflow
+			break
+		}
+		j := j1 // left child
+		if j2 := j1 + 1; j2 < n && compar(&keys[j1], &keys[j2]) >= 0 {
+			j = j2 // = 2*k + 2  // right child
+		}
+		if compar(&keys[j], &keys[i]) >= 0 {
+			break
+		}
+		k = j
+	}
+	if k == i {
+		return
+	}
+	x, v := keys[i], values[i]
+	y := uint(k + 1)
+	for p := i; p != k; {
+		j := int(y>>(bits.Len(y)-bits.Len(uint(p+1))-1)) - 1 // towards k
+		keys[p], values[p] = keys[j], values[j]
+		p = j
+	}
+	keys[k], values[k] = x, v
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestKV(t *testing.T) {
+	h := &KV[uint32]{Compar: Int32}
+	for i := 0; i < 1000; i++ {
+		k := rand.Int31n(500)
+		h.Push(k, uint32(k)*3)
+	}
+	for i := 0; i < 500; i++ {
+		j := rand.Intn(h.Len())
+		if i&1 == 0 {
+			h.Remove(j)
+		} else {
+			k := rand.Int31n(500)
+			h.Keys[j], h.Values[j] = k, uint32(k)*3
+			h.Fix(j)
+		}
+		myHeap(h.Keys).verify(t, 0)
+	}
+	for i, k := range h.Keys {
+		if h.Values[i] != uint32(k)*3 {
+			t.Fatalf("value %d of the key %d", h.Values[i], k)
+		}
+	}
+
+	last := int32(-1)
+	for h.Len() > 0 {
+		if h.Keys[0] < last || h.Values[0] != uint32(h.Keys[0])*3 {
+			t.Fatalf("%d, %d popped after %d", h.Keys[0], h.Values[0], last)
+		}
+		last = h.Keys[0]
+		h.Remove(0)
+	}
+}
+
+func TestKVHeapify(t *testing.T) {
+	h := &KV[string]{Int32, []int32{5, 3, 8, 1}, []string{"5", "3", "8", "1"}}
+	h.Heapify()
+	myHeap(h.Keys).verify(t, 0)
+	if h.Keys[0] != 1 || h.Values[0] != "1" {
+		t.Errorf("top is %d, %q", h.Keys[0], h.Values[0])
+	}
+}
+
+// compare with BenchmarkPacked of the unsafe heap
+func BenchmarkKV(b *testing.B) {
+	h := &KV[uint32]{Compar: Int32}
+	for i := 0; i < 1e5; i++ {
+		h.Push(rand.Int31(), uint32(i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Push(rand.Int31(), uint32(i))
+		h.Remove(0)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+// Layout is an arrangement of the heap tree in a slice.
+type Layout uint8
+
+const (
+	// Binary is the implicit binary heap, the children of the index i are at
+	// 2*i+1 and 2*i+2. A sift touches one cache line per level.
+	Binary Layout = iota
+	// Paged is the B-heap layout, the subtrees of several levels are packed
+	// into pages of PageSize bytes. A sift touches one page per several levels.
+	Paged
+)
+
+// PageSize is the size of a page of the Paged layout in bytes.
+const PageSize = 4096
+
+// Queue is a heap in a Layout.
+// The i-th element is at the slot Index(i) of the slice, the top is at
+// Index(0). The parent of the i-th element is the Parent(i)-th element.
+type Queue interface {
+	Len() int
+	Index(i int) int
+	Parent(i int) int
+	Push(elem *int32)
+	Remove(i int)
+	Fix(i int)
+}
+
+// New creates a heap in the layout.
+// The compar is a compare function.
+// The heap is a heapified slice, the Binary layout uses it in place, the Paged
+// layout copies it and heapifies the copy.
+func New(compar func(*int32, *int32) int, heap []int32, layout Layout) Queue {
+	if layout == Binary {
+		return &Heap{Compar: compar, Slice: heap}
+	}
+	h := &PagedHeap{Compar: compar, n: len(heap)}
+	h.Slice = make([]int32, size(PageSize/4, len(heap)))
+	for i := range heap {
+		h.Slice[h.Index(i)] = heap[i]
+	}
+	heapifyPaged( /*ts0, */ PageSize/4, compar, h.Slice, h.n)
+	return h
+}
+
+// PagedHeap is a heap in the Paged layout, it is created by New.
+// The Compar is a compare function.
+// The Slice holds the elements at the slots Index(0..Len()-1), the other
+// slots are holes. The Slice shall not be resized.
+type PagedHeap struct {
+	Compar func(*int32, *int32) int
+	Slice  []int32
+	n      int
+}
+
+// Len returns the number of the elements.
+func (h *PagedHeap) Len() int { return h.n }
+
+// Index returns the slot of the i-th element in the fill order.
+func (h *PagedHeap) Index(i int) int { return slot(PageSize/4, i+1) }
+
+// Parent returns the index of the parent of the i-th element, i > 0.
+func (h *PagedHeap) Parent(i int) int {
+	return order(PageSize/4, parent(PageSize/4, slot(PageSize/4, i+1))) - 1
+}
+
+// Push pushes the element x onto the heap.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *PagedHeap) Push(elem *int32) {
+	pushPaged( /*ts0, */ PageSize/4, h.Compar, &h.Slice, h.n, elem)
+	h.n++
+}
+
+// Remove removes the i-th element in the fill order from the heap.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *PagedHeap) Remove(i int) {
+	removePaged( /*ts0, */ PageSize/4, h.Compar, &h.Slice, h.n, i)
+	h.n--
+}
+
+// Fix re-establishes the heap ordering after the i-th element in the fill
+// order has changed its value.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *PagedHeap) Fix(i int) {
+	fixPaged( /*ts0, */ PageSize/4, h.Compar, h.Slice, h.n, i)
+}
@@ -0,0 +1,154 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestSlot(t *testing.T) {
+	for _, page := range []int{4, 8, 16, PageSize / 4} {
+		last := 0
+		for x := 1; x < 100000; x++ {
+			j := slot(page, x)
+			if j <= last || j%page == 0 || j > page && j%page == 1 {
+				t.Fatalf("page %d: slot %d of %d is taken", page, j, x)
+			}
+			last = j
+			if order(page, j) != x || size(page, x) != j+1 {
+				t.Fatalf("page %d: slot %d of %d has order %d and size %d", page, j, x, order(page, j), size(page, x))
+			}
+			if x > 1 && order(page, parent(page, j)) >= x {
+				t.Fatalf("page %d: parent of %d is filled after it", page, x)
+			}
+			if c := child(page, j); parent(page, c) != j || parent(page, c+1) != j || order(page, c) <= x {
+				t.Fatalf("page %d: children of %d are %d, %d", page, x, c, c+1)
+			}
+		}
+	}
+}
+
+// the slots of a paged heap are about its elements, not its rows of pages
+func TestPagedSize(t *testing.T) {
+	const page = PageSize / 4
+	h := New(Int32, nil, Paged).(*PagedHeap)
+	for n := 1; n <= 1<<20; n++ {
+		x := rand.Int31()
+		h.Push(&x)
+		if len(h.Slice) > n+2*n/(page-2)+page || cap(h.Slice) > 2*len(h.Slice)+page {
+			t.Fatalf("%d elements in %d slots of %d", n, len(h.Slice), cap(h.Slice))
+		}
+	}
+}
+
+func verifyQueue(t *testing.T, h Queue, s []int32) {
+	for i := 1; i < h.Len(); i++ {
+		if Int32(&s[h.Index(i)], &s[h.Index(h.Parent(i))]) < 0 {
+			t.Fatalf("heap invariant invalidated at %d", i)
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	for _, layout := range []Layout{Binary, Paged} {
+		h := New(Int32, nil, layout)
+		slice := func() []int32 {
+			if p, ok := h.(*PagedHeap); ok {
+				return p.Slice
+			}
+			return h.(*Heap).Slice
+		}
+		for i := 0; i < 20000; i++ {
+			switch n := h.Len(); {
+			case n > 0 && rand.Intn(3) == 0:
+				h.Remove(rand.Intn(n))
+			case n > 0 && rand.Intn(3) == 0:
+				j := rand.Intn(n)
+				slice()[h.Index(j)] = rand.Int31()
+				h.Fix(j)
+			default:
+				x := rand.Int31()
+				h.Push(&x)
+			}
+		}
+		verifyQueue(t, h, slice())
+
+		var last int32
+		for i := 0; h.Len() > 0; i++ {
+			x := slice()[h.Index(0)]
+			if i > 0 && Int32(&x, &last) < 0 {
+				t.Fatalf("layout %d: %d.th pop got %d after %d", layout, i, x, last)
+			}
+			last = x
+			h.Remove(0)
+		}
+		if len(slice()) != 0 {
+			t.Errorf("layout %d: %d slots left", layout, len(slice()))
+		}
+	}
+}
+
+func TestPagedMarshalBinary(t *testing.T) {
+	h := []int32{}
+	for i := 0; i < 5000; i++ {
+		x := rand.Int31()
+		Push(Int32, &h, &x)
+	}
+	p := New(Int32, h, Paged).(*PagedHeap)
+	verifyQueue(t, p, p.Slice)
+
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Heap{Compar: Int32}
+	if err := b.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	myHeap(b.Slice).verify(t, 0)
+
+	q := &PagedHeap{Compar: Int32}
+	if err := q.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	verifyQueue(t, q, q.Slice)
+
+	// the same elements
+	for len(h) > 0 {
+		if b.Slice[0] != h[0] || q.Slice[q.Index(0)] != h[0] {
+			t.Fatalf("%d and %d popped, want %d", b.Slice[0], q.Slice[q.Index(0)], h[0])
+		}
+		Remove(Int32, &h, 0)
+		Remove(Int32, &b.Slice, 0)
+		q.Remove(0)
+	}
+}
+
+// benchmarkLayout measures a remove of the top followed by a push on a heap
+// of len(src) elements.
+func benchmarkLayout(b *testing.B, layout Layout, src []int32) {
+	h := append([]int32(nil), src...)
+	q := New(Int32, h, layout)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x := rand.Int31()
+		q.Remove(0)
+		q.Push(&x)
+	}
+}
+
+func BenchmarkLayout(b *testing.B) {
+	for _, n := range []int{1e7, 1e8} {
+		src := make([]int32, n)
+		for i := range src {
+			src[i] = rand.Int31()
+		}
+		Heapify(Int32, src, src)
+		b.Run(fmt.Sprintf("Binary/%d", n), func(b *testing.B) { benchmarkLayout(b, Binary, src) })
+		b.Run(fmt.Sprintf("Paged/%d", n), func(b *testing.B) { benchmarkLayout(b, Paged, src) })
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+// The paged (B-heap) layout cuts the tree into subtrees packed into pages of
+// page slots. The root page holds the top log2(page) levels at the slots
+// 1..page-1. Any other page holds a pair of sibling subtrees one level lower,
+// rooted at the slots 2 and 3, so that siblings always share a cache line.
+// The pages are in the breadth-first order, a page has page/2 child pages.
+// The elements fill the root page and then the other pages one by one, each
+// page in the breadth-first order. So the slots grow with the fill index, only
+// the last page is partly used, and the tree is at most a page of levels
+// deeper than the complete one. The n elements are addressed by slot(page, x)
+// where x is the 1-based fill index, the holes are never compared.
+
+func pushPaged( /*ts0 *[1]uintptr, */ page int, compar func(*int32, *int32) int, heap *[]int32, n int, elem *int32) {
+	j := slot(page, n+1)
+	if m := size(page, n+1); len(*heap) < m {
+		*heap = append(*heap, make([]int32, m-len(*heap))...)
+	}
+	(*heap)[j] = *elem
+	upPaged( /*ts0, */ page, compar, *heap, j)
+}
+
+func removePaged( /*ts0 *[1]uintptr, */ page int, compar func(*int32, *int32) int, heap *[]int32, n, i int) {
+	j, last := slot(page, i+1), slot(page, n)
+	if j != last {
+		{ // swap
+			y := (*heap)[j]
+			(*heap)[j] = (*heap)[last]
+			(*heap)[last] = y
+		}
+		downPaged( /*ts0, */ page, compar, *heap, j, n-1)
+		if j != 1 {
+			upPaged( /*ts0, */ page, compar, *heap, j)
+		}
+	}
+	*heap = (*heap)[:size(page, n-1)]
+}
+
+func fixPaged( /*ts0 *[1]uintptr, */ page int, compar func(*int32, *int32) int, heap []int32, n, i int) {
+	j := slot(page, i+1)
+	downPaged( /*ts0, */ page, compar, heap, j, n)
+	upPaged( /*ts0, */ page, compar, heap, j)
+}
+
+func heapifyPaged( /*ts0 *[1]uintptr, */ page int, compar func(*int32, *int32) int, heap []int32, n int) {
+	for x := n; x > 0; x-- {
+		downPaged( /*ts0, */ page, compar, heap, slot(page, x), n)
+	}
+}
+
+// upPaged and downPaged shift the path into the hole like up and down.
+func upPaged( /*ts0 *[1]uintptr, */ page int, compar func(*int32, *int32) int, heap []int32, j int) {
+	k := j
+	for k != 1 {
+		i := parent(page, k)
+		if compar(&heap[j], &heap[i]) >= 0 {
+			break
+		}
+		k = i
+	}
+	if k == j {
+		return
+	}
+	{ // shift
+		y := heap[j]
+		for p := j; p != k; {
+			i := parent(page, p)
+			heap[p] = heap[i]
+			p = i
+		}
+		heap[k] = y
+	}
+}
+
+func downPaged( /*ts0 *[1]uintptr, */ page int, compar func(*int32, *int32) int, heap []int32, i, n int) {
+	k := i
+	for {
+		j1 := child(page, k)
+		x := order(page, j1)
+		if x > n {
+			break
+		}
+		j := j1 // left child
+		if x < n && compar(&heap[j1], &heap[j1+1]) >= 0 {
+			j = j1 + 1 // right child
+		}
+		if compar(&heap[j], &heap[i]) >= 0 {
+			break
+		}
+		k = j
+	}
+	if k == i {
+		return
+	}
+	{ // shift
+		z := heap[k]
+		for p := k; p != i; {
+			j := parent(page, p)
+			heap[j], z = z, heap[j]
+			p = j
+		}
+		heap[k] = z
+	}
+}
+
+// slot returns the slot of the 1-based fill index x.
+func slot(page, x int) int {
+	if x < page {
+		return x // the root page
+	}
+	x -= page
+	return (1+x/(page-2))*page + 2 + x%(page-2)
+}
+
+// order returns the 1-based fill index of the slot j.
+func order(page, j int) int {
+	if j < page {
+		return j // the root page
+	}
+	return page + (j/page-1)*(page-2) + j&(page-1) - 2
+}
+
+// size returns the number of the slots of a heap of n elements, up to the
+// last used slot.
+func size(page, n int) int {
+	if n == 0 {
+		return 0
+	}
+	return slot(page, n) + 1
+}
+
+// child returns the left child slot of the slot j, the right child is next.
+func child(page, j int) int {
+	l := j & (page - 1)
+	if l < page/2 {
+		return j + l
+	}
+	p := (j-l)/2 + 1 + l - page/2 // the child page
+	return p*page + 2
+}
+
+// parent returns the parent slot of the slot j.
+func parent(page, j int) int {
+	l := j & (page - 1)
+	if l > 3 || j < page {
+		return j - l + l/2
+	}
+	p := j/page - 1
+	return (p/(page/2))*page + page/2 + p%(page/2)
+}
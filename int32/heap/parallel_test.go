@@ -0,0 +1,55 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestHeapifyParallel(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 7, 10, 100, 1000, 12345} {
+		for _, workers := range []int{0, 1, 2, 3, 8, 100} {
+			h := make([]int32, n)
+			for i := range h {
+				h[i] = rand.Int31n(1000)
+			}
+			src := append([]int32(nil), h...)
+			HeapifyParallel(Int32, h, h, workers)
+			myHeap(h).verify(t, 0)
+
+			// the same elements
+			sort.Slice(src, func(i, j int) bool { return src[i] < src[j] })
+			sorted := append([]int32(nil), h...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			for i := range src {
+				if src[i] != sorted[i] {
+					t.Fatalf("n=%d workers=%d: %d at %d, want %d", n, workers, sorted[i], i, src[i])
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkHeapifyParallel(b *testing.B) {
+	src := make([]int32, 1e7)
+	for i := range src {
+		src[i] = rand.Int31()
+	}
+	h := make([]int32, len(src))
+	b.Run("Serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			copy(h, src)
+			Heapify(Int32, h, h)
+		}
+	})
+	b.Run("Parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			copy(h, src)
+			HeapifyParallel(Int32, h, h, 0)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a key-cached heap (a priority queue) of records.
+//
+// The sort key of a record is computed once, when the record is pushed, and
+// stored next to the record. The sifting compares the cached keys with plain
+// integer compares instead of calling a compare function. The records are
+// stored as [key, payload...] records of the 64-bit macro functions.
+package heap
+
+import (
+	"cmp"
+	heap64 "github.com/gomacro/heap/64/heap"
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// Heap is a key-cached heap. The zero value is not usable, use New.
+type Heap[T any] struct {
+	key   func(*T) uint64
+	ts    [1]uintptr // words per record
+	words []uint64   // records of the key and the payload
+	rec   []uint64   // scratch record
+}
+
+// New returns an empty heap ordered by the key, the least key is the top.
+// The T must not contain pointers, the payload is stored as plain words.
+func New[T any](key func(*T) uint64) *Heap[T] {
+	var t T
+	if !uheap.Plain(reflect.TypeOf(&t).Elem()) {
+		panic("New: the record type contains pointers")
+	}
+	n := 1 + (unsafe.Sizeof(t)+7)/8
+	return &Heap[T]{key: key, ts: [1]uintptr{n}, rec: make([]uint64, n)}
+}
+
+// Float converts a float key to a uint64 key of the same order, NaNs go last
+// or first depending on their sign.
+func Float[T any](key func(*T) float64) func(*T) uint64 {
+	return func(elem *T) uint64 {
+		b := math.Float64bits(key(elem))
+		if b>>63 != 0 {
+			return ^b
+		}
+		return b | 1<<63
+	}
+}
+
+// Int converts a signed key to a uint64 key of the same order.
+func Int[T any](key func(*T) int64) func(*T) uint64 {
+	return func(elem *T) uint64 {
+		return uint64(key(elem)) ^ 1<<63
+	}
+}
+
+func compar(a, b *uint64) int {
+	return cmp.Compare(*a, *b)
+}
+
+// Len returns the number of the records.
+func (h *Heap[T]) Len() int {
+	return len(h.words) / int(h.ts[0])
+}
+
+// At returns the i-th record, the record at 0 is the top. A changed record
+// must be fixed with Fix.
+func (h *Heap[T]) At(i int) *T {
+	return (*T)(unsafe.Pointer(&h.words[i*int(h.ts[0])+1]))
+}
+
+// Key returns the cached key of the i-th record.
+func (h *Heap[T]) Key(i int) uint64 {
+	return h.words[i*int(h.ts[0])]
+}
+
+// Peek returns the top record without removing it, nil if the heap is empty.
+func (h *Heap[T]) Peek() *T {
+	if len(h.words) == 0 {
+		return nil
+	}
+	return h.At(0)
+}
+
+// Push pushes the record onto the heap, the key is computed once.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap[T]) Push(elem *T) {
+	h.rec[0] = h.key(elem)
+	copy(bytes(h.rec[1:]), unsafe.Slice((*byte)(unsafe.Pointer(elem)), unsafe.Sizeof(*elem)))
+	heap64.Push(&h.ts, compar, &h.words, h.rec)
+}
+
+// Pop removes and returns the top record, false if the heap is empty.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap[T]) Pop() (elem T, ok bool) {
+	if len(h.words) == 0 {
+		return elem, false
+	}
+	elem = *h.At(0)
+	heap64.Remove(&h.ts, compar, &h.words, 0)
+	return elem, true
+}
+
+// Remove removes the i-th record from the heap.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap[T]) Remove(i int) {
+	heap64.Remove(&h.ts, compar, &h.words, i)
+}
+
+// Fix computes the key of the i-th record again and re-establishes the heap
+// ordering after the record has changed.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap[T]) Fix(i int) {
+	h.words[i*int(h.ts[0])] = h.key(h.At(i))
+	heap64.Fix(&h.ts, compar, h.words, i)
+}
+
+func bytes(w []uint64) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(w))), 8*len(w))
+}
@@ -0,0 +1,136 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+type point struct {
+	X, Y float64
+	ID   uint32
+	Tag  [3]byte
+}
+
+func dist(p *point) float64 {
+	return math.Sqrt(p.X*p.X + p.Y*p.Y)
+}
+
+func Dist(a, b *point) int {
+	da, db := dist(a), dist(b)
+	if da < db {
+		return -1
+	}
+	if da > db {
+		return 1
+	}
+	return 0
+}
+
+func TestPushPop(t *testing.T) {
+	h := New(Float(dist))
+	var want []float64
+	for i := 0; i < 1000; i++ {
+		p := point{rand.NormFloat64(), rand.NormFloat64(), uint32(i), [3]byte{1, 2, 3}}
+		h.Push(&p)
+		want = append(want, dist(&p))
+	}
+	sort.Float64s(want)
+
+	for i, w := range want {
+		p, ok := h.Pop()
+		if !ok || dist(&p) != w || p.Tag != [3]byte{1, 2, 3} {
+			t.Fatalf("%d.th pop got %v; want %v", i, p, w)
+		}
+	}
+	if _, ok := h.Pop(); ok || h.Peek() != nil {
+		t.Errorf("pop from an empty heap")
+	}
+}
+
+func TestKeys(t *testing.T) {
+	floats := []float64{math.Inf(-1), -2.5, -1, -0.1, 0, 0.1, 1, 2.5, math.Inf(1)}
+	f := Float(func(x *float64) float64 { return *x })
+	for i := 1; i < len(floats); i++ {
+		if f(&floats[i-1]) >= f(&floats[i]) {
+			t.Errorf("float key of %v is not less than of %v", floats[i-1], floats[i])
+		}
+	}
+	ints := []int64{math.MinInt64, -5, -1, 0, 1, 5, math.MaxInt64}
+	g := Int(func(x *int64) int64 { return *x })
+	for i := 1; i < len(ints); i++ {
+		if g(&ints[i-1]) >= g(&ints[i]) {
+			t.Errorf("int key of %v is not less than of %v", ints[i-1], ints[i])
+		}
+	}
+}
+
+func TestFix(t *testing.T) {
+	h := New(func(x *uint32) uint64 { return uint64(*x) })
+	for _, x := range []uint32{5, 3, 8, 1, 9} {
+		h.Push(&x)
+	}
+	for i := 0; i < h.Len(); i++ {
+		if *h.At(i) == 8 {
+			*h.At(i) = 0
+			h.Fix(i)
+		}
+	}
+	if *h.Peek() != 0 || h.Key(0) != 0 {
+		t.Errorf("top is %d", *h.Peek())
+	}
+	h.Remove(0)
+	var got []uint32
+	for h.Len() > 0 {
+		x, _ := h.Pop()
+		got = append(got, x)
+	}
+	if len(got) != 4 || got[0] != 1 || got[3] != 9 {
+		t.Errorf("Has %v", got)
+	}
+}
+
+func TestPointers(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("a record with pointers accepted")
+		}
+	}()
+	New(func(s *string) uint64 { return uint64(len(*s)) })
+}
+
+// the key-cached heap and the unsafe heap with the same order computed by
+// the compare function on every touch
+func BenchmarkKeyed(b *testing.B) {
+	h := New(Float(dist))
+	for i := 0; i < 1e5; i++ {
+		p := point{X: rand.NormFloat64(), Y: rand.NormFloat64()}
+		h.Push(&p)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := point{X: rand.NormFloat64(), Y: rand.NormFloat64()}
+		h.Push(&p)
+		h.Pop()
+	}
+}
+
+func BenchmarkCompar(b *testing.B) {
+	var h []point
+	for i := 0; i < 1e5; i++ {
+		p := point{X: rand.NormFloat64(), Y: rand.NormFloat64()}
+		uheap.Push(Dist, &h, &p)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := point{X: rand.NormFloat64(), Y: rand.NormFloat64()}
+		uheap.Push(Dist, &h, &p)
+		uheap.Remove(Dist, &h, 0)
+	}
+}
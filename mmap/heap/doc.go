@@ -0,0 +1,14 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a heap (a priority queue) of fixed-size records in a
+// memory-mapped file. It is available on Linux.
+//
+// The records are not loaded into the Go heap. The unsafe/heap Push, Remove
+// and Fix run directly on the mapping, the file grows by remapping.
+//
+// The file starts with an unsafe/heap encoding header padded to a page, the
+// records follow in the host byte order. The count in the header is updated
+// by every operation.
+package heap
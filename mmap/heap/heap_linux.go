@@ -0,0 +1,216 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"io"
+	"os"
+	"reflect"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	page        = 4096
+	countOffset = 12 // offset of the count in the unsafe/heap header
+	minRecords  = 64
+)
+
+// Heap is a heap of fixed-size records in a memory-mapped file.
+// The T must not contain pointers.
+type Heap[T any] struct {
+	compar func(*T, *T) int
+	f      *os.File
+	data   []byte // the mapping, the header page and the records
+	heap   []T    // the records, the capacity ends at the end of the mapping
+}
+
+// Open opens the heap file at path, creating it if needed.
+// The compar is a compare function.
+// An existing file must hold the records of the same size.
+func Open[T any](path string, compar func(*T, *T) int) (*Heap[T], error) {
+	var zero T
+	size := unsafe.Sizeof(zero)
+	if !uheap.Plain(reflect.TypeOf(zero)) || size == 0 {
+		return nil, fmt.Errorf("heap: %T is not a fixed-size record", zero)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	h := &Heap[T]{compar: compar, f: f}
+	if err := h.open(size); err != nil {
+		h.unmap()
+		f.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Heap[T]) open(size uintptr) error {
+	fi, err := h.f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() == 0 {
+		hdr := uheap.Header{Width: uheap.Width(size), Ordered: true, Size: uint32(size)}
+		var b bytes.Buffer
+		uheap.WriteHeader(&b, &hdr)
+		if _, err := h.f.WriteAt(b.Bytes(), 0); err != nil {
+			return err
+		}
+		return h.remap(minRecords)
+	}
+
+	// validate the file before mapping it, a foreign file is never resized
+	hdr, err := uheap.ReadHeader(io.NewSectionReader(h.f, 0, page))
+	if err == io.ErrUnexpectedEOF {
+		return uheap.ErrFormat
+	}
+	if err != nil {
+		return err
+	}
+	if uintptr(hdr.Size) != size {
+		return fmt.Errorf("heap: element size %d, want %d", hdr.Size, size)
+	}
+	n := (fi.Size() - page) / int64(size)
+	if fi.Size() < page || (fi.Size()-page)%int64(size) != 0 || hdr.Count > uint64(n) {
+		return uheap.ErrFormat
+	}
+	if err := h.mmap(int(n)); err != nil {
+		return err
+	}
+	h.heap = h.heap[:hdr.Count]
+	if !hdr.Ordered && len(h.heap) > 0 {
+		uheap.Heapify(h.compar, h.heap, h.heap)
+		h.data[6] = 1
+	}
+	return nil
+}
+
+// Len returns the number of the records in the heap.
+func (h *Heap[T]) Len() int {
+	return len(h.heap)
+}
+
+// Slice returns the heapified records, Slice()[0] is the top.
+// The slice is a view of the mapping, it is valid until the next Push or
+// Close. A record changed in place must be passed to Fix.
+func (h *Heap[T]) Slice() []T {
+	return h.heap
+}
+
+// Push pushes the element onto the heap, the file grows if needed.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap[T]) Push(elem *T) error {
+	if len(h.heap) == cap(h.heap) {
+		if err := h.remap(2 * cap(h.heap)); err != nil {
+			return err
+		}
+	}
+	uheap.Push(h.compar, &h.heap, elem)
+	h.count()
+	return nil
+}
+
+// Remove removes the element at index i from the heap.
+// The file does not shrink.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap[T]) Remove(i int) {
+	uheap.Remove(h.compar, &h.heap, i)
+	h.count()
+}
+
+// Fix re-establishes the heap ordering after the element at index i has
+// changed its value.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap[T]) Fix(i int) {
+	uheap.Fix(h.compar, h.heap, i)
+}
+
+// Sync flushes the mapping to the file.
+func (h *Heap[T]) Sync() error {
+	if h.data == nil {
+		return os.ErrClosed
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC,
+		uintptr(unsafe.Pointer(&h.data[0])), uintptr(len(h.data)), syscall.MS_SYNC)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Close flushes the mapping, unmaps it and closes the file. Closing a closed
+// heap does nothing.
+func (h *Heap[T]) Close() error {
+	if h.f == nil {
+		return nil
+	}
+	var err error
+	if h.data != nil {
+		err = h.Sync()
+	}
+	if uerr := h.unmap(); err == nil {
+		err = uerr
+	}
+	if cerr := h.f.Close(); err == nil {
+		err = cerr
+	}
+	h.f = nil
+	return err
+}
+
+func (h *Heap[T]) count() {
+	binary.LittleEndian.PutUint64(h.data[countOffset:], uint64(len(h.heap)))
+}
+
+// remap grows the file to hold n records and maps it again. The old mapping
+// is kept until the new one is in place, so a failure leaves the heap usable.
+func (h *Heap[T]) remap(n int) error {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	if n < minRecords {
+		n = minRecords
+	}
+	if h.f == nil {
+		return os.ErrClosed
+	}
+	if err := h.f.Truncate(int64(page + n*size)); err != nil {
+		return err
+	}
+	return h.mmap(n)
+}
+
+// mmap maps the header page and n records of the file in place of the old
+// mapping, the length of the records is kept.
+func (h *Heap[T]) mmap(n int) error {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	data, err := syscall.Mmap(int(h.f.Fd()), 0, page+n*size,
+		syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+	l := len(h.heap)
+	err = h.unmap()
+	h.data = data
+	h.heap = unsafe.Slice((*T)(unsafe.Add(unsafe.Pointer(&data[0]), page)), n)[:l]
+	return err
+}
+
+func (h *Heap[T]) unmap() error {
+	if h.data == nil {
+		return nil
+	}
+	err := syscall.Munmap(h.data)
+	h.data, h.heap = nil, nil
+	return err
+}
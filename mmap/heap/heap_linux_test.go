@@ -0,0 +1,166 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"bytes"
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+type record struct {
+	Key  uint32
+	Seq  uint32
+	Data [4]byte
+}
+
+func Record(a, b *record) int {
+	if a.Key != b.Key {
+		return int(a.Key) - int(b.Key)
+	}
+	return int(a.Seq) - int(b.Seq)
+}
+
+func Rgb(a, b *[3]byte) int {
+	for i := range a {
+		if r := int(a[i]) - int(b[i]); r != 0 {
+			return r
+		}
+	}
+	return 0
+}
+
+func TestPushRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heap")
+	h, err := Open(path, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []record
+	for i := 0; i < 1000; i++ { // grows the file a few times
+		x := record{uint32(rand.Intn(100)), uint32(i), [4]byte{byte(i)}}
+		if err := h.Push(&x); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, x)
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err = Open(path, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if h.Len() != len(want) {
+		t.Fatalf("Len() = %d; want %d", h.Len(), len(want))
+	}
+
+	// change a few records in place
+	for i := 0; i < 10; i++ {
+		j := rand.Intn(h.Len())
+		s := h.Slice()
+		for k := range want {
+			if want[k] == s[j] {
+				want[k].Key += 50
+			}
+		}
+		s[j].Key += 50
+		h.Fix(j)
+	}
+
+	sort.Slice(want, func(i, j int) bool { return Record(&want[i], &want[j]) < 0 })
+	for i := range want {
+		if x := h.Slice()[0]; x != want[i] {
+			t.Fatalf("%d.th pop got %v; want %v", i, x, want[i])
+		}
+		h.Remove(0)
+	}
+}
+
+func TestOddSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heap")
+	h, err := Open(path, Rgb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 300; i++ {
+		x := [3]byte{byte(rand.Intn(256)), byte(i), 0}
+		h.Push(&x)
+	}
+	h.Remove(5)
+	h.Close()
+
+	h, err = Open(path, Rgb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if h.Len() != 299 {
+		t.Fatalf("Len() = %d; want 299", h.Len())
+	}
+	s := h.Slice()
+	for i := 1; i < len(s); i++ {
+		if Rgb(&s[i], &s[(i-1)/2]) < 0 {
+			t.Fatalf("heap invariant invalidated at %d", i)
+		}
+	}
+}
+
+func TestSizeMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heap")
+	h, err := Open(path, Rgb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	if _, err := Open(path, Record); err == nil {
+		t.Errorf("a record size mismatch accepted")
+	}
+}
+
+func TestForeign(t *testing.T) {
+	for _, size := range []int{5, 10000} {
+		path := filepath.Join(t.TempDir(), "foreign")
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		if err := os.WriteFile(path, data, 0o666); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(path, Rgb); err != uheap.ErrFormat {
+			t.Errorf("%d bytes: Open error %v; want ErrFormat", size, err)
+		}
+		if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+			t.Errorf("%d bytes: the foreign file changed to %d bytes", size, len(got))
+		}
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	h, err := Open(filepath.Join(t.TempDir(), "heap"), Rgb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if err := h.Sync(); err != os.ErrClosed {
+		t.Errorf("Sync after Close: %v; want os.ErrClosed", err)
+	}
+	x := [3]byte{1, 2, 3}
+	if err := h.Push(&x); err != os.ErrClosed {
+		t.Errorf("Push after Close: %v; want os.ErrClosed", err)
+	}
+}
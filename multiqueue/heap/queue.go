@@ -0,0 +1,115 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a relaxed concurrent priority queue (a MultiQueue).
+//
+// The queue is a set of sub-heaps, each one behind its own lock. A Push goes
+// to a random sub-heap, a Pop takes the better top of two random sub-heaps.
+// The workers rarely meet on a lock, but a Pop returns one of the smallest
+// elements rather than the smallest one. With one sub-heap the queue is an
+// exact, locked priority queue.
+//
+// The sub-heaps are sliced and sifted by the unsafe/heap Push and Remove.
+package heap
+
+import (
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// Queue is a relaxed concurrent priority queue. The zero value is not usable,
+// use New.
+type Queue[T any] struct {
+	compar func(*T, *T) int
+	subs   []sub[T]
+}
+
+type sub[T any] struct {
+	mu   sync.Mutex
+	heap []T
+	_    [64 - 32]byte // keep the sub-heaps on separate cache lines
+}
+
+// New returns an empty queue of the queues sub-heaps, 2*GOMAXPROCS if the
+// queues is less than 1. The compar is a compare function, it must be safe
+// for concurrent use.
+func New[T any](compar func(*T, *T) int, queues int) *Queue[T] {
+	if queues < 1 {
+		queues = 2 * runtime.GOMAXPROCS(0)
+	}
+	return &Queue[T]{compar: compar, subs: make([]sub[T], queues)}
+}
+
+// Len returns the number of the elements. It is not a snapshot when the
+// queue is used concurrently.
+func (q *Queue[T]) Len() (n int) {
+	for i := range q.subs {
+		s := &q.subs[i]
+		s.mu.Lock()
+		n += len(s.heap)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Push pushes the element onto a random sub-heap.
+// The complexity is O(log(n)) where n is the length of the sub-heap.
+func (q *Queue[T]) Push(elem T) {
+	s := &q.subs[rand.Intn(len(q.subs))]
+	s.mu.Lock()
+	uheap.Push(q.compar, &s.heap, &elem)
+	s.mu.Unlock()
+}
+
+// Pop removes and returns the better top of two random sub-heaps, false if
+// the queue is empty. When both sub-heaps are empty, the other ones are
+// scanned, so an element pushed before the Pop started is not missed.
+// The complexity is O(log(n)) where n is the length of the sub-heap.
+func (q *Queue[T]) Pop() (elem T, ok bool) {
+	if len(q.subs) > 1 {
+		i := rand.Intn(len(q.subs))
+		j := rand.Intn(len(q.subs) - 1)
+		if j >= i {
+			j++
+		}
+		a, b := &q.subs[i], &q.subs[j]
+		if i > j {
+			a, b = b, a // lock in the index order
+		}
+		a.mu.Lock()
+		b.mu.Lock()
+		s := a
+		if len(a.heap) == 0 || len(b.heap) > 0 && q.compar(&b.heap[0], &a.heap[0]) < 0 {
+			s = b
+		}
+		if len(s.heap) > 0 {
+			elem, ok = s.heap[0], true
+			uheap.Remove(q.compar, &s.heap, 0)
+		}
+		b.mu.Unlock()
+		a.mu.Unlock()
+		if ok {
+			return elem, ok
+		}
+	}
+	for i := range q.subs {
+		if elem, ok = q.subs[i].pop(q.compar); ok {
+			return elem, ok
+		}
+	}
+	return elem, false
+}
+
+func (s *sub[T]) pop(compar func(*T, *T) int) (elem T, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.heap) == 0 {
+		return elem, false
+	}
+	elem = s.heap[0]
+	uheap.Remove(compar, &s.heap, 0)
+	return elem, true
+}
@@ -0,0 +1,128 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"cmp"
+	"fmt"
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func Uint64(a, b *uint64) int {
+	return cmp.Compare(*a, *b)
+}
+
+func TestExact(t *testing.T) {
+	q := New(Uint64, 1)
+	for i := 0; i < 1000; i++ {
+		q.Push(uint64(rand.Intn(100)))
+	}
+	if q.Len() != 1000 {
+		t.Fatalf("Len %d, want 1000", q.Len())
+	}
+	last := uint64(0)
+	for i := 0; i < 1000; i++ {
+		x, ok := q.Pop()
+		if !ok || x < last {
+			t.Fatalf("%d, %v popped after %d", x, ok, last)
+		}
+		last = x
+	}
+	if _, ok := q.Pop(); ok {
+		t.Fatal("popped from an empty queue")
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	q := New(Uint64, 8)
+	for i := 0; i < 100; i++ {
+		q.Push(uint64(i))
+		if x, ok := q.Pop(); !ok || x != uint64(i) {
+			t.Fatalf("%d, %v popped, want %d", x, ok, i)
+		}
+	}
+}
+
+// every pushed element is popped exactly once
+func TestConcurrent(t *testing.T) {
+	const workers, n = 8, 10000
+	for _, queues := range []int{0, 1, 2, 4, 16} {
+		q := New(Uint64, queues)
+		var seen [workers * n]int32
+		var popped atomic.Int64
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(2)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < n; i++ {
+					q.Push(uint64(w*n + i))
+				}
+			}(w)
+			go func() {
+				defer wg.Done()
+				for popped.Load() < workers*n {
+					if x, ok := q.Pop(); ok {
+						atomic.AddInt32(&seen[x], 1)
+						popped.Add(1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		for x, c := range seen {
+			if c != 1 {
+				t.Fatalf("queues=%d: %d popped %d times", queues, x, c)
+			}
+		}
+		if q.Len() != 0 {
+			t.Fatalf("queues=%d: Len %d after the drain", queues, q.Len())
+		}
+	}
+}
+
+// BenchmarkQueue runs a Push and a Pop per op on 8 goroutines per CPU, the
+// Locked is a single mutex around an unsafe/heap.
+func BenchmarkQueue(b *testing.B) {
+	for _, queues := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprint("Queues", queues), func(b *testing.B) {
+			q := New(Uint64, queues)
+			for i := 0; i < 1e5; i++ {
+				q.Push(rand.Uint64())
+			}
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					q.Push(rand.Uint64())
+					q.Pop()
+				}
+			})
+		})
+	}
+	b.Run("Locked", func(b *testing.B) {
+		var mu sync.Mutex
+		var h []uint64
+		for i := 0; i < 1e5; i++ {
+			x := rand.Uint64()
+			uheap.Push(Uint64, &h, &x)
+		}
+		b.SetParallelism(8)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				x := rand.Uint64()
+				mu.Lock()
+				uheap.Push(Uint64, &h, &x)
+				uheap.Remove(Uint64, &h, 0)
+				mu.Unlock()
+			}
+		})
+	})
+}
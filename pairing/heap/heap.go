@@ -0,0 +1,158 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a pairing heap (a mergeable priority queue).
+//
+// The heap is ordered using the same compare function as the slice heaps,
+// a func(*T, *T) int returning a negative number when the first element
+// goes first.
+package heap
+
+// Node is an element pushed onto the heap. It is a handle that stays valid
+// until the element is popped, also when the heap is melded into another.
+type Node[T any] struct {
+	Elem T
+
+	child *Node[T] // leftmost child
+	next  *Node[T] // right sibling
+	prev  *Node[T] // left sibling, or the parent of the leftmost child
+}
+
+// Heap is a pairing heap. The zero value is not usable, use New.
+type Heap[T any] struct {
+	compar func(*T, *T) int
+	root   *Node[T]
+	n      int
+}
+
+// New returns an empty heap.
+// The compar is a compare function.
+func New[T any](compar func(*T, *T) int) *Heap[T] {
+	return &Heap[T]{compar: compar}
+}
+
+// Len returns the number of elements in the heap.
+func (h *Heap[T]) Len() int {
+	return h.n
+}
+
+// Top returns the top element without removing it, nil if the heap is empty.
+// The complexity is O(1).
+func (h *Heap[T]) Top() *Node[T] {
+	return h.root
+}
+
+// Push pushes the element onto the heap and returns its handle.
+// The complexity is O(1).
+func (h *Heap[T]) Push(elem *T) *Node[T] {
+	x := &Node[T]{Elem: *elem}
+	h.root = h.link(h.root, x)
+	h.n++
+	return x
+}
+
+// Pop removes the top element and returns it, nil if the heap is empty.
+// The complexity is O(log(n)) amortized where n = h.Len().
+func (h *Heap[T]) Pop() *Node[T] {
+	x := h.root
+	if x == nil {
+		return nil
+	}
+	h.root = h.combine(x.child)
+	h.n--
+	x.child = nil
+	return x
+}
+
+// Meld moves all the elements of the other heap to this heap. The other heap
+// is left empty, the handles of its elements stay valid in this heap.
+// Both heaps must use the same compare function.
+// The complexity is O(1).
+func (h *Heap[T]) Meld(other *Heap[T]) {
+	if h == other {
+		return
+	}
+	h.root = h.link(h.root, other.root)
+	h.n += other.n
+	other.root, other.n = nil, 0
+}
+
+// DecreaseKey sets the element of the node to elem, which must not go after
+// the current element.
+// The complexity is O(log(n)) amortized, O(1) in practice.
+func (h *Heap[T]) DecreaseKey(x *Node[T], elem *T) {
+	if h.compar(elem, &x.Elem) > 0 {
+		panic("DecreaseKey: the new element goes after the old one")
+	}
+	x.Elem = *elem
+	if x == h.root {
+		return
+	}
+	h.cut(x)
+	h.root = h.link(h.root, x)
+}
+
+// cut detaches the subtree rooted at x from its parent and siblings.
+func (h *Heap[T]) cut(x *Node[T]) {
+	if x.prev.child == x {
+		x.prev.child = x.next
+	} else {
+		x.prev.next = x.next
+	}
+	if x.next != nil {
+		x.next.prev = x.prev
+	}
+	x.next, x.prev = nil, nil
+}
+
+// link links two detached trees, the loser becomes the leftmost child.
+func (h *Heap[T]) link(a, b *Node[T]) *Node[T] {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if h.compar(&b.Elem, &a.Elem) < 0 {
+		a, b = b, a
+	}
+	b.prev = a
+	b.next = a.child
+	if a.child != nil {
+		a.child.prev = b
+	}
+	a.child = b
+	return a
+}
+
+// combine links a list of siblings into one tree using the two-pass pairing.
+func (h *Heap[T]) combine(first *Node[T]) *Node[T] {
+	// first pass: link the pairs left to right, chain the winners in reverse
+	var pairs *Node[T]
+	for first != nil {
+		a, b := first, first.next
+		first = nil
+		if b != nil {
+			first = b.next
+			b.next, b.prev = nil, nil
+		}
+		a.next, a.prev = nil, nil
+		a = h.link(a, b)
+		a.next = pairs
+		pairs = a
+	}
+	if pairs == nil {
+		return nil
+	}
+
+	// second pass: link the winners right to left
+	root := pairs
+	pairs, root.next = root.next, nil
+	for pairs != nil {
+		p := pairs
+		pairs, p.next = p.next, nil
+		root = h.link(root, p)
+	}
+	return root
+}
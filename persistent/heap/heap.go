@@ -0,0 +1,101 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a persistent leftist heap (an immutable priority
+// queue). Every operation returns a new version of the heap and leaves the
+// old versions intact, the versions share their common nodes.
+//
+// The heap is ordered using the same compare function as the slice heaps,
+// a func(*T, *T) int returning a negative number when the first element
+// goes first.
+package heap
+
+type node[T any] struct {
+	elem  T
+	left  *node[T]
+	right *node[T]
+	rank  int // length of the right spine
+	n     int // number of the elements in the subtree
+}
+
+// Heap is a version of a persistent heap. The zero value is not usable, use
+// New. Heap is a small value, copy it freely.
+type Heap[T any] struct {
+	compar func(*T, *T) int
+	root   *node[T]
+}
+
+// New returns an empty heap.
+// The compar is a compare function.
+func New[T any](compar func(*T, *T) int) Heap[T] {
+	return Heap[T]{compar: compar}
+}
+
+// Len returns the number of elements in the heap.
+func (h Heap[T]) Len() int {
+	if h.root == nil {
+		return 0
+	}
+	return h.root.n
+}
+
+// Top returns the top element, nil if the heap is empty.
+// The element is shared by the versions, it must not be modified.
+// The complexity is O(1).
+func (h Heap[T]) Top() *T {
+	if h.root == nil {
+		return nil
+	}
+	return &h.root.elem
+}
+
+// Push returns a new version of the heap with the element pushed onto it.
+// The complexity is O(log(n)) where n = h.Len().
+func (h Heap[T]) Push(elem *T) Heap[T] {
+	h.root = h.merge(h.root, &node[T]{elem: *elem, rank: 1, n: 1})
+	return h
+}
+
+// Pop returns a new version of the heap without the top element.
+// The complexity is O(log(n)) where n = h.Len().
+func (h Heap[T]) Pop() Heap[T] {
+	if h.root != nil {
+		h.root = h.merge(h.root.left, h.root.right)
+	}
+	return h
+}
+
+// Merge returns a new version of the heap with all the elements of the other
+// heap. Both heaps must use the same compare function.
+// The complexity is O(log(n)) where n is the length of the resulting heap.
+func (h Heap[T]) Merge(other Heap[T]) Heap[T] {
+	h.root = h.merge(h.root, other.root)
+	return h
+}
+
+// merge merges two trees along their right spines, copying the visited nodes.
+func (h Heap[T]) merge(a, b *node[T]) *node[T] {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if h.compar(&b.elem, &a.elem) < 0 {
+		a, b = b, a
+	}
+	x := &node[T]{elem: a.elem, left: a.left, right: h.merge(a.right, b), n: a.n + b.n}
+	if rank(x.left) < rank(x.right) {
+		x.left, x.right = x.right, x.left
+	}
+	x.rank = rank(x.right) + 1
+	return x
+}
+
+func rank[T any](x *node[T]) int {
+	if x == nil {
+		return 0
+	}
+	return x.rank
+}
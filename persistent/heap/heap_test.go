@@ -0,0 +1,94 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Int32(a, b *int32) int {
+	return int(*a) - int(*b)
+}
+
+func drain(t *testing.T, h Heap[int32], want []int32) {
+	want = append([]int32(nil), want...)
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+	if h.Len() != len(want) {
+		t.Fatalf("Len() = %d; want %d", h.Len(), len(want))
+	}
+	for i := range want {
+		if x := h.Top(); x == nil || *x != want[i] {
+			t.Fatalf("%d.th top got %v; want %d", i, x, want[i])
+		}
+		h = h.Pop()
+	}
+	if h.Top() != nil || h.Len() != 0 {
+		t.Errorf("heap not empty")
+	}
+}
+
+func TestPushPop(t *testing.T) {
+	h := New(Int32)
+	var want []int32
+	for i := 0; i < 1000; i++ {
+		x := int32(rand.Intn(100))
+		h = h.Push(&x)
+		want = append(want, x)
+	}
+	drain(t, h, want)
+
+	x, y := *h.Top(), *h.Pop().Top()
+	drain(t, h.Pop().Pop().Push(&y).Push(&x), want)
+}
+
+func TestVersions(t *testing.T) {
+	parent := New(Int32)
+	var elems []int32
+	for i := 0; i < 100; i++ {
+		x := int32(rand.Intn(1000))
+		parent = parent.Push(&x)
+		elems = append(elems, x)
+	}
+
+	// the branches derived from one parent do not see each other
+	a, b := parent.Pop(), parent
+	for i := int32(0); i < 50; i++ {
+		x, y := -i, 1000+i
+		b = b.Push(&x)
+		a = a.Push(&y).Pop()
+	}
+	drain(t, parent, elems)
+
+	var wantB []int32
+	for i := int32(0); i < 50; i++ {
+		wantB = append(wantB, -i)
+	}
+	drain(t, b, append(wantB, elems...))
+	if a.Len() != 99 {
+		t.Errorf("a.Len() = %d; want 99", a.Len())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := New(Int32), New(Int32)
+	var want []int32
+	for i := 0; i < 300; i++ {
+		x := int32(rand.Intn(1000))
+		if i%3 == 0 {
+			a = a.Push(&x)
+		} else {
+			b = b.Push(&x)
+		}
+		want = append(want, x)
+	}
+	la, lb := a.Len(), b.Len()
+	m := a.Merge(b)
+	drain(t, m, want)
+	if a.Len() != la || b.Len() != lb {
+		t.Errorf("the merged versions changed")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a monotone radix heap (a priority queue of integer
+// keys that never go below the last popped key).
+//
+// Monotone priorities such as the Dijkstra distances or the timestamps of an
+// event loop are popped in O(log(C)) amortized, where C is the key range, with
+// no compare function at all.
+package heap
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// Key is the type of the keys.
+type Key interface {
+	~uint32 | ~uint64 | ~int32
+}
+
+// ErrMonotone is returned by Push for a key below the last popped key.
+var ErrMonotone = errors.New("heap: key below the last popped key")
+
+type item[V any] struct {
+	key   uint64 // order-preserving mapping of the key
+	value V
+}
+
+// Heap is a monotone radix heap of the keys with a payload each.
+// The zero value is an empty heap.
+type Heap[K Key, V any] struct {
+	// the bucket i holds the keys whose highest bit that differs from the
+	// last popped key is the bit i-1, the bucket 0 holds the last popped key
+	buckets [65][]item[V]
+	last    uint64
+	popped  bool
+	n       int
+}
+
+// Len returns the number of the elements in the heap.
+func (h *Heap[K, V]) Len() int {
+	return h.n
+}
+
+// Last returns the last popped key, the zero key before the first Pop.
+func (h *Heap[K, V]) Last() (key K) {
+	if h.popped {
+		key = unmap[K](h.last)
+	}
+	return key
+}
+
+// Push pushes the key with its value onto the heap. The key must not go
+// below the last popped key.
+// The complexity is O(1).
+func (h *Heap[K, V]) Push(key K, value V) error {
+	k := mapkey(key)
+	if k < h.last {
+		return ErrMonotone
+	}
+	b := bits.Len64(k ^ h.last)
+	h.buckets[b] = append(h.buckets[b], item[V]{k, value})
+	h.n++
+	return nil
+}
+
+// Top returns the top key and its value without removing them.
+// The ok is false if the heap is empty.
+// The complexity is O(n) in the worst case, O(1) after a Pop of an equal key.
+func (h *Heap[K, V]) Top() (key K, value V, ok bool) {
+	if h.n == 0 {
+		return key, value, false
+	}
+	if b := h.buckets[0]; len(b) > 0 {
+		x := b[len(b)-1]
+		return unmap[K](x.key), x.value, true
+	}
+	b := h.buckets[h.first()]
+	m := 0
+	for i := range b {
+		if b[i].key < b[m].key {
+			m = i
+		}
+	}
+	return unmap[K](b[m].key), b[m].value, true
+}
+
+// Pop removes the top key and its value and returns them.
+// The ok is false if the heap is empty.
+// The complexity is O(log(C)) amortized where C is the key range.
+func (h *Heap[K, V]) Pop() (key K, value V, ok bool) {
+	if h.n == 0 {
+		return key, value, false
+	}
+	if len(h.buckets[0]) == 0 {
+		h.redistribute(h.first())
+	}
+	b := h.buckets[0]
+	x := b[len(b)-1]
+	var zero item[V]
+	b[len(b)-1] = zero
+	h.buckets[0] = b[:len(b)-1]
+	h.popped = true
+	h.n--
+	return unmap[K](x.key), x.value, true
+}
+
+// first returns the first nonempty bucket.
+func (h *Heap[K, V]) first() int {
+	i := 1
+	for len(h.buckets[i]) == 0 {
+		i++
+	}
+	return i
+}
+
+// redistribute makes the least key of the bucket i the last popped key and
+// moves the keys of the bucket to the lower buckets.
+func (h *Heap[K, V]) redistribute(i int) {
+	b := h.buckets[i]
+	h.last = b[0].key
+	for j := range b {
+		h.last = min(h.last, b[j].key)
+	}
+	for j := range b {
+		d := bits.Len64(b[j].key ^ h.last)
+		h.buckets[d] = append(h.buckets[d], b[j])
+	}
+	clear(b)
+	h.buckets[i] = b[:0]
+}
+
+func mapkey[K Key](k K) uint64 {
+	var zero K
+	if ^zero < 0 { // signed
+		return uint64(int64(k)) ^ 1<<63
+	}
+	return uint64(k)
+}
+
+func unmap[K Key](k uint64) K {
+	var zero K
+	if ^zero < 0 { // signed
+		return K(int64(k ^ 1<<63))
+	}
+	return K(k)
+}
@@ -0,0 +1,124 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	heap32 "github.com/gomacro/heap/int32/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func testPushPop[K Key](t *testing.T, keys []K) {
+	var h Heap[K, int]
+	for i, k := range keys {
+		if err := h.Push(k, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sorted := append([]K(nil), keys...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for i := range sorted {
+		top, _, _ := h.Top()
+		k, v, ok := h.Pop()
+		if !ok || k != sorted[i] || top != k || keys[v] != k {
+			t.Fatalf("%d.th pop got %v %v; want %v", i, k, ok, sorted[i])
+		}
+		if h.Last() != k {
+			t.Fatalf("Last() = %v; want %v", h.Last(), k)
+		}
+	}
+	if _, _, ok := h.Pop(); ok || h.Len() != 0 {
+		t.Errorf("heap not empty")
+	}
+}
+
+func TestPushPop(t *testing.T) {
+	u32 := []uint32{math.MaxUint32, 0, 7}
+	u64 := []uint64{math.MaxUint64, 0, 1 << 40}
+	i32 := []int32{math.MinInt32, math.MaxInt32, -1, 0, 1}
+	for i := 0; i < 1000; i++ {
+		u32 = append(u32, rand.Uint32())
+		u64 = append(u64, rand.Uint64())
+		i32 = append(i32, int32(rand.Uint32()))
+	}
+	testPushPop(t, u32)
+	testPushPop(t, u64)
+	testPushPop(t, i32)
+}
+
+func TestMonotone(t *testing.T) {
+	var h Heap[int32, string]
+	h.Push(-5, "a")
+	h.Push(10, "b")
+	h.Push(3, "c")
+	if k, v, _ := h.Pop(); k != -5 || v != "a" {
+		t.Errorf("pop got %d %s", k, v)
+	}
+	if err := h.Push(-6, "d"); err != ErrMonotone {
+		t.Errorf("Push below the last popped key: %v", err)
+	}
+	if err := h.Push(-5, "e"); err != nil {
+		t.Errorf("Push of the last popped key: %v", err)
+	}
+	for _, want := range []int32{-5, 3, 10} {
+		if k, _, _ := h.Pop(); k != want {
+			t.Errorf("pop got %d; want %d", k, want)
+		}
+	}
+}
+
+// the workload of the benchmarks: pop the top, push a few keys above it
+const (
+	benchPush = 3
+	benchSpan = 1000
+)
+
+func BenchmarkRadix(b *testing.B) {
+	var h Heap[int32, int32]
+	for i := 0; i < 10000; i++ {
+		h.Push(int32(rand.Intn(benchSpan)), int32(i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k, v, _ := h.Pop()
+		for j := 0; j < benchPush; j++ {
+			h.Push(k+int32(rand.Intn(benchSpan)), v)
+		}
+		if h.Len() > 20000 {
+			for h.Len() > 10000 {
+				h.Pop()
+			}
+		}
+	}
+}
+
+func Int32(a, b *int32) int {
+	return int(*a) - int(*b)
+}
+
+func BenchmarkInt32(b *testing.B) {
+	h := []int32{}
+	for i := 0; i < 10000; i++ {
+		x := int32(rand.Intn(benchSpan))
+		heap32.Push(Int32, &h, &x)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := h[0]
+		heap32.Remove(Int32, &h, 0)
+		for j := 0; j < benchPush; j++ {
+			x := k + int32(rand.Intn(benchSpan))
+			heap32.Push(Int32, &h, &x)
+		}
+		if len(h) > 20000 {
+			for len(h) > 10000 {
+				heap32.Remove(Int32, &h, 0)
+			}
+		}
+	}
+}
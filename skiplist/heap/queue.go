@@ -0,0 +1,198 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a lock-free priority queue on a skiplist.
+//
+// The queue follows Lindén and Jonsson, "A Skiplist-Based Concurrent Priority
+// Queue with Minimal Memory Contention". A node is deleted by a mark on the
+// bottom link of its predecessor, so the deleted nodes form a prefix of the
+// list. A Pop claims the first live node with a single compare-and-swap of
+// that link, a Push links its node with a compare-and-swap of an unmarked
+// link. Both are linearizable. The deleted prefix is unlinked in batches.
+//
+// The queue is ordered using a compare function, the equal elements are
+// popped in the order of their pushes.
+package heap
+
+import (
+	"math/bits"
+	"math/rand"
+	"sync/atomic"
+)
+
+const (
+	levels = 32 // the levels of the head
+	bound  = 32 // the deleted nodes passed by a Pop before they are unlinked
+)
+
+// Queue is a lock-free priority queue. The zero value is not usable, use New.
+type Queue[T any] struct {
+	compar func(*T, *T) int
+	head   *node[T]
+	seq    atomic.Uint64 // the order of the equal elements
+}
+
+type node[T any] struct {
+	elem T
+	seq  uint64
+	next []link[T]
+}
+
+// link is a link of a level, the bottom one marks the deletion of its node
+type link[T any] struct {
+	p atomic.Pointer[ref[T]]
+}
+
+type ref[T any] struct {
+	node *node[T]
+	mark bool // the node is deleted, on the bottom level only
+}
+
+func (l *link[T]) load() *ref[T] {
+	return l.p.Load()
+}
+
+// deleted reports whether the successor of the n at the bottom level is
+// deleted. The n is then deleted too, unless it is the last deleted node.
+func (n *node[T]) deleted() bool {
+	return n.next[0].load().mark
+}
+
+// New returns an empty queue. The compar is a compare function, it must be
+// safe for concurrent use.
+func New[T any](compar func(*T, *T) int) *Queue[T] {
+	q := &Queue[T]{compar: compar, head: &node[T]{next: make([]link[T], levels)}}
+	for i := range q.head.next {
+		q.head.next[i].p.Store(&ref[T]{})
+	}
+	return q
+}
+
+func (q *Queue[T]) less(a, b *node[T]) bool {
+	if c := q.compar(&a.elem, &b.elem); c != 0 {
+		return c < 0
+	}
+	return a.seq < b.seq
+}
+
+// Push pushes the element onto the queue.
+// The expected complexity is O(log(n)) where n is the length of the queue.
+func (q *Queue[T]) Push(elem T) {
+	level := 1 + bits.TrailingZeros32(rand.Uint32()|1<<(levels-1))
+	n := &node[T]{elem: elem, seq: q.seq.Add(1), next: make([]link[T], level)}
+	var preds, succs [levels]*node[T]
+
+	// the bottom link is the linearization point
+	for {
+		q.locate(n, &preds, &succs)
+		r := preds[0].next[0].load()
+		if r.mark || r.node != succs[0] {
+			continue
+		}
+		n.next[0].p.Store(&ref[T]{node: succs[0]})
+		if preds[0].next[0].p.CompareAndSwap(r, &ref[T]{node: n}) {
+			break
+		}
+	}
+
+	// the upper levels only speed up the search
+	for i := 1; i < level; i++ {
+		for {
+			if n.deleted() {
+				return // popped meanwhile
+			}
+			n.next[i].p.Store(&ref[T]{node: succs[i]})
+			r := preds[i].next[i].load()
+			if r.node == succs[i] && preds[i].next[i].p.CompareAndSwap(r, &ref[T]{node: n}) {
+				break
+			}
+			q.locate(n, &preds, &succs)
+		}
+	}
+}
+
+// locate finds the predecessors and the successors of the n on every level.
+// It passes the deleted nodes, so the n goes behind the deleted prefix even
+// when it is less than the deleted nodes.
+func (q *Queue[T]) locate(n *node[T], preds, succs *[levels]*node[T]) {
+	x := q.head
+	for i := levels - 1; i >= 0; i-- {
+		r := x.next[i].load()
+		for next := r.node; next != nil && next != n; next = r.node {
+			if !q.less(next, n) && !next.deleted() && !(i == 0 && r.mark) {
+				break
+			}
+			x = next
+			r = x.next[i].load()
+		}
+		preds[i], succs[i] = x, r.node
+	}
+}
+
+// Peek returns the least element without removing it, false if the queue is
+// empty.
+// The complexity is O(1) plus the deleted nodes not yet unlinked.
+func (q *Queue[T]) Peek() (elem T, ok bool) {
+	x := q.head
+	for {
+		r := x.next[0].load()
+		if !r.mark {
+			if r.node == nil {
+				return elem, false
+			}
+			return r.node.elem, true
+		}
+		x = r.node
+	}
+}
+
+// Pop removes and returns the least element, false if the queue is empty.
+// The complexity is O(1) plus the deleted nodes not yet unlinked, the
+// unlinking is amortized over the Pops.
+func (q *Queue[T]) Pop() (elem T, ok bool) {
+	x := q.head
+	obs := x.next[0].load()
+	offset := 0
+	for {
+		r := x.next[0].load()
+		if r.mark {
+			x = r.node
+			offset++
+			continue
+		}
+		if r.node == nil {
+			return elem, false
+		}
+		if x.next[0].p.CompareAndSwap(r, &ref[T]{node: r.node, mark: true}) {
+			x = r.node
+			break
+		}
+	}
+
+	// the x is the last deleted node now, unlink the ones before it
+	if offset >= bound && q.head.next[0].p.CompareAndSwap(obs, &ref[T]{node: x, mark: true}) {
+		q.restructure()
+	}
+	return x.elem, true
+}
+
+// restructure moves the upper links of the head behind the deleted nodes.
+func (q *Queue[T]) restructure() {
+	x := q.head
+	for i := levels - 1; i > 0; {
+		h := q.head.next[i].load()
+		if h.node == nil || !h.node.deleted() {
+			i--
+			continue
+		}
+		r := x.next[i].load()
+		for r.node != nil && r.node.deleted() {
+			x = r.node
+			r = x.next[i].load()
+		}
+		if q.head.next[i].p.CompareAndSwap(h, &ref[T]{node: r.node}) {
+			i--
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"cmp"
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func Uint64(a, b *uint64) int {
+	return cmp.Compare(*a, *b)
+}
+
+type pair struct {
+	key, id int
+}
+
+func Pair(a, b *pair) int {
+	return cmp.Compare(a.key, b.key)
+}
+
+func TestSerial(t *testing.T) {
+	q := New(Uint64)
+	if _, ok := q.Peek(); ok {
+		t.Fatal("peeked into an empty queue")
+	}
+	var all []uint64
+	for i := 0; i < 10000; i++ {
+		x := uint64(rand.Intn(1000))
+		all = append(all, x)
+		q.Push(x)
+		if i%3 == 0 {
+			y, _ := q.Peek()
+			z, ok := q.Pop()
+			sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+			if !ok || y != all[0] || z != all[0] {
+				t.Fatalf("%d peeked, %d, %v popped, want %d", y, z, ok, all[0])
+			}
+			all = all[1:]
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	for _, want := range all {
+		if x, ok := q.Pop(); !ok || x != want {
+			t.Fatalf("%d, %v popped, want %d", x, ok, want)
+		}
+	}
+	if _, ok := q.Pop(); ok {
+		t.Fatal("popped from an empty queue")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	q := New(Pair)
+	for i := 0; i < 1000; i++ {
+		q.Push(pair{i % 3, i})
+	}
+	last := pair{-1, -1}
+	for i := 0; i < 1000; i++ {
+		p, _ := q.Pop()
+		if p.key < last.key || p.key == last.key && p.id < last.id {
+			t.Fatalf("%v popped after %v", p, last)
+		}
+		last = p
+	}
+}
+
+// every pushed element is popped exactly once
+func TestConcurrent(t *testing.T) {
+	const workers, n = 8, 20000
+	q := New(Uint64)
+	var seen [workers * n]int32
+	var popped atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				q.Push(uint64(rand.Intn(workers*n))*workers*n + uint64(w*n+i))
+				if i%8 == 0 {
+					q.Peek()
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for popped.Load() < workers*n {
+				if x, ok := q.Pop(); ok {
+					atomic.AddInt32(&seen[x%(workers*n)], 1)
+					popped.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for x, c := range seen {
+		if c != 1 {
+			t.Fatalf("%d popped %d times", x, c)
+		}
+	}
+	if _, ok := q.Peek(); ok {
+		t.Fatal("peeked into a drained queue")
+	}
+}
+
+// with the increasing pushes the least element never decreases, so every
+// consumer pops and peeks increasing elements
+func TestLinearizable(t *testing.T) {
+	const workers, n = 4, 50000
+	q := New(Uint64)
+	var done atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := uint64(0)
+			for {
+				finished := done.Load()
+				if x, ok := q.Peek(); ok && x < last {
+					t.Errorf("%d peeked after %d", x, last)
+					return
+				}
+				x, ok := q.Pop()
+				if !ok {
+					if finished {
+						return
+					}
+					continue
+				}
+				if x <= last {
+					t.Errorf("%d popped after %d", x, last)
+					return
+				}
+				last = x
+			}
+		}()
+	}
+	for i := 1; i <= n; i++ {
+		q.Push(uint64(i))
+	}
+	done.Store(true)
+	wg.Wait()
+}
+
+// BenchmarkQueue runs a Push and a Pop per op on 8 goroutines per CPU, the
+// Locked is a single mutex around an unsafe/heap.
+func BenchmarkQueue(b *testing.B) {
+	b.Run("SkipList", func(b *testing.B) {
+		q := New(Uint64)
+		for i := 0; i < 1e5; i++ {
+			q.Push(rand.Uint64())
+		}
+		b.SetParallelism(8)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				q.Push(rand.Uint64())
+				q.Pop()
+			}
+		})
+	})
+	b.Run("Locked", func(b *testing.B) {
+		var mu sync.Mutex
+		var h []uint64
+		for i := 0; i < 1e5; i++ {
+			x := rand.Uint64()
+			uheap.Push(Uint64, &h, &x)
+		}
+		b.SetParallelism(8)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				x := rand.Uint64()
+				mu.Lock()
+				uheap.Push(Uint64, &h, &x)
+				uheap.Remove(Uint64, &h, 0)
+				mu.Unlock()
+			}
+		})
+	})
+}
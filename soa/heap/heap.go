@@ -0,0 +1,201 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+// Package heap provides a struct-of-arrays heap (a priority queue).
+//
+// The int32 keys are kept in one contiguous slice and ordered like the
+// int32/heap package does. The payloads are kept in parallel columns, the
+// rows of the columns are moved in lockstep with the keys. The sifting
+// touches only the keys, a payload row is moved once per level it climbs
+// or sinks.
+package heap
+
+import (
+	"math/bits"
+)
+
+// Column is a payload column, a slice moved in lockstep with the keys.
+// Use Of to make one.
+type Column interface {
+	len() int
+	save(i int)
+	move(dst, src int)
+	restore(i int)
+	truncate(n int)
+}
+
+type column[T any] struct {
+	s   *[]T
+	tmp T
+}
+
+// Of makes a column of the slice s. The slice is owned by the heap, the rows
+// may be read and written in place, and appended to before Push.
+func Of[T any](s *[]T) Column {
+	return &column[T]{s: s}
+}
+
+func (c *column[T]) len() int          { return len(*c.s) }
+func (c *column[T]) save(i int)        { c.tmp = (*c.s)[i] }
+func (c *column[T]) move(dst, src int) { (*c.s)[dst] = (*c.s)[src] }
+func (c *column[T]) restore(i int)     { (*c.s)[i] = c.tmp }
+func (c *column[T]) truncate(n int) {
+	var zero T
+	for i := n; i < len(*c.s); i++ {
+		(*c.s)[i] = zero // drop the references
+	}
+	*c.s = (*c.s)[:n]
+}
+
+// Heap is a struct-of-arrays heap.
+// The Compar is a compare function of the keys.
+// The Keys is a heapified slice, the row i of every column belongs to the
+// Keys[i].
+type Heap struct {
+	Compar func(*int32, *int32) int
+	Keys   []int32
+	cols   []Column
+}
+
+// New returns a heap over the columns. The columns must be of an equal
+// length, the keys are zeroes until Heapify is called.
+func New(compar func(*int32, *int32) int, cols ...Column) *Heap {
+	h := &Heap{Compar: compar, cols: cols}
+	if len(cols) > 0 {
+		h.Keys = make([]int32, cols[0].len())
+	}
+	h.check(len(h.Keys))
+	return h
+}
+
+// Len returns the number of the rows.
+func (h *Heap) Len() int {
+	return len(h.Keys)
+}
+
+// Push pushes the key of the row last appended to the columns.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap) Push(key int32) {
+	h.Keys = append(h.Keys, key)
+	h.check(len(h.Keys))
+	h.up(len(h.Keys) - 1)
+}
+
+// Remove removes the row i from the heap and from the columns.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap) Remove(i int) {
+	n := len(h.Keys) - 1
+	if n != i {
+		h.Keys[i] = h.Keys[n]
+		for _, c := range h.cols {
+			c.move(i, n)
+		}
+		h.down(i, n)
+		if i != 0 {
+			h.up(i)
+		}
+	}
+	h.Keys = h.Keys[:n]
+	for _, c := range h.cols {
+		c.truncate(n)
+	}
+}
+
+// Fix re-establishes the heap ordering after the key at index i has changed
+// its value.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *Heap) Fix(i int) {
+	h.down(i, len(h.Keys))
+	h.up(i)
+}
+
+// Heapify establishes the heap ordering of the Keys and the columns.
+// Its complexity is O(n) where n = h.Len().
+func (h *Heap) Heapify() {
+	n := len(h.Keys)
+	h.check(n)
+	for i := n/2 - 1; i >= 0; i-- {
+		h.down(i, n)
+	}
+}
+
+func (h *Heap) check(n int) {
+	for _, c := range h.cols {
+		if c.len() != n {
+			panic("heap: the columns and the keys differ in length")
+		}
+	}
+}
+
+// up and down keep the key in place while its slot is searched, then shift
+// the path into the hole, moving the keys and the rows once.
+func (h *Heap) up(j int) {
+	compar, keys := h.Compar, h.Keys
+	k := j
+	for k > 0 {
+		i := (k - 1) / 2 // parent
+		if compar(&keys[j], &keys[i]) >= 0 {
+			break
+		}
+		k = i
+	}
+	if k == j {
+		return
+	}
+	x := keys[j]
+	for _, c := range h.cols {
+		c.save(j)
+	}
+	for p := j; p != k; {
+		i := (p - 1) / 2
+		keys[p] = keys[i]
+		for _, c := range h.cols {
+			c.move(p, i)
+		}
+		p = i
+	}
+	keys[k] = x
+	for _, c := range h.cols {
+		c.restore(k)
+	}
+}
+
+func (h *Heap) down(i, n int) {
+	compar, keys := h.Compar, h.Keys
+	k := i
+	for {
+		j1 := 2*k + 1
+		if j1 >= n || j1 < 0 { // j1 < 0 after int32 overflow
+			break
+		}
+		j := j1 // left child
+		if j2 := j1 + 1; j2 < n && compar(&keys[j1], &keys[j2]) >= 0 {
+			j = j2 // = 2*k + 2  // right child
+		}
+		if compar(&keys[j], &keys[i]) >= 0 {
+			break
+		}
+		k = j
+	}
+	if k == i {
+		return
+	}
+	x := keys[i]
+	for _, c := range h.cols {
+		c.save(i)
+	}
+	y := uint(k + 1)
+	for p := i; p != k; {
+		j := int(y>>(bits.Len(y)-bits.Len(uint(p+1))-1)) - 1 // towards k
+		keys[p] = keys[j]
+		for _, c := range h.cols {
+			c.move(p, j)
+		}
+		p = j
+	}
+	keys[k] = x
+	for _, c := range h.cols {
+		c.restore(k)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	uheap "github.com/gomacro/heap/unsafe/heap"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func Int32(a, b *int32) int {
+	return int(*a) - int(*b)
+}
+
+func verify(t *testing.T, h *Heap, names []string, ids []int64) {
+	for i := 1; i < h.Len(); i++ {
+		if h.Keys[i] < h.Keys[(i-1)/2] {
+			t.Fatalf("heap invariant invalidated at %d", i)
+		}
+	}
+	for i, k := range h.Keys {
+		if names[i] != strconv.Itoa(int(k)) || ids[i] != int64(k)*2 {
+			t.Fatalf("row %d is %q, %d; want the key %d", i, names[i], ids[i], k)
+		}
+	}
+}
+
+func TestLockstep(t *testing.T) {
+	var names []string
+	var ids []int64
+	h := New(Int32, Of(&names), Of(&ids))
+	push := func(k int32) {
+		names = append(names, strconv.Itoa(int(k)))
+		ids = append(ids, int64(k)*2)
+		h.Push(k)
+	}
+	for i := 0; i < 1000; i++ {
+		push(rand.Int31n(500))
+	}
+	verify(t, h, names, ids)
+
+	for i := 0; i < 500; i++ {
+		j := rand.Intn(h.Len())
+		if i&1 == 0 {
+			h.Remove(j)
+		} else {
+			k := rand.Int31n(500)
+			h.Keys[j], names[j], ids[j] = k, strconv.Itoa(int(k)), int64(k)*2
+			h.Fix(j)
+		}
+		verify(t, h, names, ids)
+	}
+
+	last := int32(-1)
+	for h.Len() > 0 {
+		if h.Keys[0] < last || names[0] != strconv.Itoa(int(h.Keys[0])) {
+			t.Fatalf("%d, %q popped after %d", h.Keys[0], names[0], last)
+		}
+		last = h.Keys[0]
+		h.Remove(0)
+	}
+	if len(names) != 0 || len(ids) != 0 {
+		t.Errorf("columns left %d, %d", len(names), len(ids))
+	}
+}
+
+func TestHeapify(t *testing.T) {
+	names := []string{"5", "3", "8", "1", "9", "2"}
+	ids := []int64{10, 6, 16, 2, 18, 4}
+	h := New(Int32, Of(&names), Of(&ids))
+	copy(h.Keys, []int32{5, 3, 8, 1, 9, 2})
+	h.Heapify()
+	verify(t, h, names, ids)
+}
+
+func TestLength(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("a push without a row accepted")
+		}
+	}()
+	var names []string
+	New(Int32, Of(&names)).Push(1)
+}
+
+type payload [60]byte
+
+type record struct {
+	Key     int32
+	Payload payload
+}
+
+func Record(a, b *record) int {
+	return int(a.Key) - int(b.Key)
+}
+
+// the struct-of-arrays heap and the unsafe heap of the same records
+func BenchmarkSoA(b *testing.B) {
+	var payloads []payload
+	h := New(Int32, Of(&payloads))
+	for i := 0; i < 1e5; i++ {
+		payloads = append(payloads, payload{})
+		h.Push(rand.Int31())
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		payloads = append(payloads, payload{})
+		h.Push(rand.Int31())
+		h.Remove(0)
+	}
+}
+
+func BenchmarkAoS(b *testing.B) {
+	var h []record
+	for i := 0; i < 1e5; i++ {
+		uheap.Push(Record, &h, &record{Key: rand.Int31()})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		uheap.Push(Record, &h, &record{Key: rand.Int31()})
+		uheap.Remove(Record, &h, 0)
+	}
+}
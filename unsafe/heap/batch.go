@@ -0,0 +1,115 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	heap32 "github.com/gomacro/heap/32/heap"
+	heap64 "github.com/gomacro/heap/64/heap"
+	heap8 "github.com/gomacro/heap/8/heap"
+)
+
+// PushAll pushes all the elems onto the heap.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// The elems is a slice of the same element type.
+// Small batches are sifted up one by one, large batches re-heapify the
+// subtrees they were appended to.
+// The complexity is O(min(k*log(n), k+log(n)*log(n))) where k = len(elems).
+func PushAll(compar interface{}, heap interface{}, elems interface{}) {
+	size := elemsize2(heap) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		uheap, fheap := su64(heap, m[0])
+
+		heap64.PushAll(&m, arg64(compar), &uheap, u64(elems, m[0]))
+		fu64(uheap, fheap, m[0])
+		return
+	}
+	if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		uheap, fheap := su32(heap, m[0])
+
+		heap32.PushAll(&m, arg32(compar), &uheap, u32(elems, m[0]))
+		fu32(uheap, fheap, m[0])
+		return
+	}
+
+	// use 1 (8bit)
+	var m = [1]uintptr{size}
+	uheap, fheap := su8(heap, m[0])
+
+	heap8.PushAll(&m, arg8(compar), &uheap, u8(elems, m[0]))
+	fu8(uheap, fheap, m[0])
+}
+
+// Merge merges the src heap into the heap. The src is left untouched.
+// The compar is a compare function.
+// The heap is a pointer to a heapified slice, the src is a heapified slice
+// of the same element type.
+// The larger of the two heaps keeps its place, the smaller one is pushed
+// onto it with PushAll.
+// The complexity is O(min(k*log(n), k+log(n)*log(n))) where k is the length
+// of the smaller heap.
+func Merge(compar interface{}, heap interface{}, src interface{}) {
+	size := elemsize2(heap) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		uheap, fheap := su64(heap, m[0])
+
+		heap64.Merge(&m, arg64(compar), &uheap, u64(src, m[0]))
+		fu64(uheap, fheap, m[0])
+		return
+	}
+	if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		uheap, fheap := su32(heap, m[0])
+
+		heap32.Merge(&m, arg32(compar), &uheap, u32(src, m[0]))
+		fu32(uheap, fheap, m[0])
+		return
+	}
+
+	// use 1 (8bit)
+	var m = [1]uintptr{size}
+	uheap, fheap := su8(heap, m[0])
+
+	heap8.Merge(&m, arg8(compar), &uheap, u8(src, m[0]))
+	fu8(uheap, fheap, m[0])
+}
+
+// RemoveAll removes the elements at the indices from the heap in one pass.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// The indices may be unsorted, a duplicate index is removed once.
+// The complexity is O(min(k*log(n)*log(n), n)) where k = len(indices).
+func RemoveAll(compar interface{}, heap interface{}, indices []int) {
+	size := elemsize2(heap) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		uheap, fheap := su64(heap, m[0])
+
+		heap64.RemoveAll(&m, arg64(compar), &uheap, indices)
+		fu64(uheap, fheap, m[0])
+		return
+	}
+	if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		uheap, fheap := su32(heap, m[0])
+
+		heap32.RemoveAll(&m, arg32(compar), &uheap, indices)
+		fu32(uheap, fheap, m[0])
+		return
+	}
+
+	// use 1 (8bit)
+	var m = [1]uintptr{size}
+	uheap, fheap := su8(heap, m[0])
+
+	heap8.RemoveAll(&m, arg8(compar), &uheap, indices)
+	fu8(uheap, fheap, m[0])
+}
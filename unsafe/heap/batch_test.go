@@ -0,0 +1,168 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Uint64(a, b *uint64) int {
+	if *a < *b {
+		return -1
+	}
+	if *a > *b {
+		return 1
+	}
+	return 0
+}
+
+func Rgb(a, b *[3]byte) int {
+	for i := range a {
+		if r := int(a[i]) - int(b[i]); r != 0 {
+			return r
+		}
+	}
+	return 0
+}
+
+func ordered[T any](h []T, compar func(*T, *T) int) bool {
+	for i := 1; i < len(h); i++ {
+		if compar(&h[i], &h[(i-1)/2]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPushAll(t *testing.T) {
+	for _, k := range []int{0, 1, 3, 10, 100, 1000} {
+		h := []uint32{}
+		for i := uint32(0); i < 100; i++ {
+			x := uint32(rand.Intn(1000))
+			Push(Uint32, &h, &x)
+		}
+		elems := make([]uint32, k)
+		for i := range elems {
+			elems[i] = uint32(rand.Intn(1000))
+		}
+		PushAll(Uint32, &h, elems)
+		myHeap(h).verify(t, 0)
+		if len(h) != 100+k {
+			t.Errorf("len(h) = %d; want %d", len(h), 100+k)
+		}
+	}
+}
+
+func TestPushAllWidths(t *testing.T) {
+	h64 := []uint64{5, 7}
+	PushAll(Uint64, &h64, []uint64{9, 1, 3, 8, 2, 6, 4, 0})
+	if !ordered(h64, Uint64) || len(h64) != 10 {
+		t.Errorf("Has %v", h64)
+	}
+
+	h8 := [][3]byte{}
+	PushAll(Rgb, &h8, [][3]byte{{3, 1, 2}, {1, 2, 3}, {3, 1, 1}, {2, 2, 2}, {0, 9, 9}})
+	if !ordered(h8, Rgb) || len(h8) != 5 || h8[0] != [3]byte{0, 9, 9} {
+		t.Errorf("Has %v", h8)
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	for _, k := range []int{0, 1, 2, 5, 50, 199, 200} {
+		h := []uint32{}
+		for i := uint32(0); i < 200; i++ {
+			Push(Uint32, &h, &i)
+		}
+		indices := rand.Perm(len(h))[:k]
+		indices = append(indices, indices...) // duplicates are removed once
+
+		m := make(map[uint32]bool)
+		for _, i := range indices {
+			m[h[i]] = true
+		}
+		RemoveAll(Uint32, &h, indices)
+		myHeap(h).verify(t, 0)
+
+		if len(h) != 200-k {
+			t.Fatalf("len(h) = %d; want %d", len(h), 200-k)
+		}
+		for _, x := range h {
+			if m[x] {
+				t.Errorf("%d was not removed", x)
+			}
+		}
+		sorted := append([]uint32(nil), h...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		for i := 1; i < len(sorted); i++ {
+			if sorted[i] == sorted[i-1] {
+				t.Errorf("%d is duplicated", sorted[i])
+			}
+		}
+	}
+}
+
+func TestRemoveAllWidths(t *testing.T) {
+	h64 := []uint64{}
+	PushAll(Uint64, &h64, []uint64{9, 1, 3, 8, 2, 6, 4, 0, 5, 7})
+	RemoveAll(Uint64, &h64, []int{0, 9, 4})
+	if !ordered(h64, Uint64) || len(h64) != 7 {
+		t.Errorf("Has %v", h64)
+	}
+
+	h8 := [][3]byte{}
+	PushAll(Rgb, &h8, [][3]byte{{3, 1, 2}, {1, 2, 3}, {3, 1, 1}, {2, 2, 2}, {0, 9, 9}})
+	RemoveAll(Rgb, &h8, []int{0, 1})
+	if !ordered(h8, Rgb) || len(h8) != 3 {
+		t.Errorf("Has %v", h8)
+	}
+}
+
+func BenchmarkPushAll(b *testing.B) {
+	const n = 10000
+	elems := make([]uint32, n)
+	for i := range elems {
+		elems[i] = uint32(rand.Intn(n))
+	}
+	h := make([]uint32, 0, n)
+	for i := 0; i < b.N; i++ {
+		h = h[:0]
+		PushAll(Uint32, &h, elems)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	for _, sizes := range [][2]int{{0, 0}, {0, 10}, {10, 0}, {100, 3}, {3, 100}, {50, 60}} {
+		h, src := []uint32{}, []uint32{}
+		for i := 0; i < sizes[0]; i++ {
+			x := uint32(rand.Intn(1000))
+			Push(Uint32, &h, &x)
+		}
+		for i := 0; i < sizes[1]; i++ {
+			x := uint32(rand.Intn(1000))
+			Push(Uint32, &src, &x)
+		}
+		Merge(Uint32, &h, src)
+		myHeap(h).verify(t, 0)
+		if len(h) != sizes[0]+sizes[1] {
+			t.Errorf("len(h) = %d; want %d", len(h), sizes[0]+sizes[1])
+		}
+	}
+}
+
+func TestMergeWidths(t *testing.T) {
+	h64 := []uint64{3, 5}
+	Merge(Uint64, &h64, []uint64{0, 1, 2, 4, 6})
+	if !ordered(h64, Uint64) || len(h64) != 7 || h64[0] != 0 {
+		t.Errorf("Has %v", h64)
+	}
+
+	h8 := [][3]byte{{1, 2, 3}}
+	Merge(Rgb, &h8, [][3]byte{{0, 9, 9}, {3, 1, 1}})
+	if !ordered(h8, Rgb) || len(h8) != 3 || h8[0] != [3]byte{0, 9, 9} {
+		t.Errorf("Has %v", h8)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	heap32 "github.com/gomacro/heap/32/heap"
+	heap64 "github.com/gomacro/heap/64/heap"
+	heap8 "github.com/gomacro/heap/8/heap"
+)
+
+// RemoveBottomUp removes the element at index i from the heap like Remove,
+// but sifts down bottom-up. It descends along the smaller children to a leaf
+// and climbs back, taking about half the compares of Remove. It pays off when
+// the compare function is expensive, such as a string or a Composite compare.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// The complexity is O(log(n)) where n = h.Len().
+func RemoveBottomUp(compar interface{}, heap interface{}, i int) {
+	size := elemsize2(heap) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		uheap, fheap := su64(heap, m[0])
+
+		heap64.RemoveBottomUp(&m, arg64(compar), &uheap, i)
+		fu64(uheap, fheap, m[0])
+		return
+	}
+	if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		uheap, fheap := su32(heap, m[0])
+
+		heap32.RemoveBottomUp(&m, arg32(compar), &uheap, i)
+		fu32(uheap, fheap, m[0])
+		return
+	}
+
+	// use 1 (8bit)
+	var m = [1]uintptr{size}
+	uheap, fheap := su8(heap, m[0])
+
+	heap8.RemoveBottomUp(&m, arg8(compar), &uheap, i)
+	fu8(uheap, fheap, m[0])
+}
+
+// HeapifyBottomUp initializes the heap like Heapify, but sifts down
+// bottom-up, see RemoveBottomUp.
+// The compar is a compare function.
+// Then heap is a source slice. Dst is a result slice. In place is supported.
+// Its complexity is O(n) where n = h.Len().
+func HeapifyBottomUp(compar interface{}, dst interface{}, heap interface{}) {
+	size := elemsize(heap) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		heap64.HeapifyBottomUp(&m, arg64(compar), u64(dst, m[0]), u64(heap, m[0]))
+		return
+	}
+	if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		heap32.HeapifyBottomUp(&m, arg32(compar), u32(dst, m[0]), u32(heap, m[0]))
+		return
+	}
+
+	// use 1 (8bit)
+	var m = [1]uintptr{size}
+	heap8.HeapifyBottomUp(&m, arg8(compar), u8(dst, m[0]), u8(heap, m[0]))
+}
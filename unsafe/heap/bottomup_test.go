@@ -0,0 +1,108 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"bytes"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testBottomUp[T any](t *testing.T, compar func(*T, *T) int, gen func() T) {
+	for _, n := range []int{1, 2, 3, 10, 100, 1000} {
+		h := make([]T, n)
+		for i := range h {
+			h[i] = gen()
+		}
+		HeapifyBottomUp(compar, h, h)
+		if !ordered(h, compar) {
+			t.Fatalf("%T: HeapifyBottomUp is not heap-ordered", h)
+		}
+		for len(h) > 0 {
+			RemoveBottomUp(compar, &h, rand.Intn(len(h)))
+			if !ordered(h, compar) {
+				t.Fatalf("%T: RemoveBottomUp is not heap-ordered", h)
+			}
+		}
+	}
+}
+
+func TestBottomUp(t *testing.T) {
+	testBottomUp(t, Uint32, func() uint32 { return uint32(rand.Intn(100)) })
+	testBottomUp(t, Uint64, func() uint64 { return uint64(rand.Intn(100)) })
+	testBottomUp(t, Rgb, func() [3]byte { return [3]byte{byte(rand.Intn(4)), byte(rand.Intn(4)), 0} })
+	testBottomUp(t, String, func() string { return strconv.Itoa(rand.Intn(100)) })
+}
+
+func String(a, b *string) int {
+	return strings.Compare(*a, *b)
+}
+
+// TestUnmarshalBottomUp heapifies an unordered encoding with the BottomUp of
+// the receiver, the bottom-up heapify is told by its fewer compares.
+func TestUnmarshalBottomUp(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	s := make([]uint64, 1000)
+	for i := range s {
+		s[i] = r.Uint64()
+	}
+	var b bytes.Buffer
+	if err := Encode(&b, s, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var compares [2]int
+	for i, bottomUp := range []bool{false, true} {
+		var g []uint64
+		h := &Heap{Compar: func(a, b *uint64) int {
+			compares[i]++
+			return Uint64(a, b)
+		}, Slice: &g, BottomUp: bottomUp}
+		if err := h.UnmarshalBinary(b.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		if !ordered(g, Uint64) {
+			t.Errorf("bottom-up %v: not heap-ordered", bottomUp)
+		}
+	}
+	if compares[1] >= compares[0] {
+		t.Errorf("bottom-up compares %d, top-down %d", compares[1], compares[0])
+	}
+}
+
+// BenchmarkBottomUp reports the compares per element of a heapify followed
+// by a drain of 1e5 strings with a common prefix.
+func BenchmarkBottomUp(b *testing.B) {
+	var compares int
+	compar := func(a, b *string) int {
+		compares++
+		return String(a, b)
+	}
+	src := make([]string, 1e5)
+	for i := range src {
+		src[i] = "gomacro/heap/" + strconv.Itoa(rand.Int())
+	}
+
+	for _, bottomUp := range []bool{false, true} {
+		name := "TopDown"
+		if bottomUp {
+			name = "BottomUp"
+		}
+		b.Run(name, func(b *testing.B) {
+			compares = 0
+			for i := 0; i < b.N; i++ {
+				s := append([]string(nil), src...)
+				h := &Heap{Compar: compar, Slice: &s, BottomUp: bottomUp}
+				h.Heapify()
+				for h.Len() > 0 {
+					h.Remove(0)
+				}
+			}
+			b.ReportMetric(float64(compares)/float64(b.N*len(src)), "compares/elem")
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"cmp"
+	"reflect"
+	"unsafe"
+)
+
+// Key is one sort key of a composite compare function.
+// The Offset is a field offset, usually unsafe.Offsetof(elem.field).
+// The Kind is a field kind, any of the bool, integer, float and string kinds.
+// The Desc reverses the order of the key.
+type Key struct {
+	Offset uintptr
+	Kind   reflect.Kind
+	Desc   bool
+}
+
+// Composite builds a compare function that orders elements by the keys in
+// turn. The next key is consulted only when the previous keys are equal.
+// The compar is a pointer to a func(*T, *T) int variable, it is overwritten.
+// The built function can be passed to Push, Remove, Fix and Heapify.
+//
+//	var compar func(*Job, *Job) int
+//	Composite(&compar,
+//		Key{unsafe.Offsetof(Job{}.Prio), reflect.Uint16, true},
+//		Key{unsafe.Offsetof(Job{}.Seq), reflect.Uint32, false})
+func Composite(compar interface{}, keys ...Key) {
+	v := reflect.ValueOf(compar)
+	if v.Kind() != reflect.Ptr || v.Type().Elem().Kind() != reflect.Func {
+		panic("Composite: compar is not a pointer to a compare function")
+	}
+	f := v.Type().Elem()
+	if f.NumIn() != 2 || f.NumOut() != 1 || f.In(0) != f.In(1) ||
+		f.In(0).Kind() != reflect.Ptr || f.Out(0).Kind() != reflect.Int {
+		panic("Composite: compar is not a func(*T, *T) int")
+	}
+	size := f.In(0).Elem().Size()
+
+	k := make([]Key, len(keys))
+	copy(k, keys)
+	for i := range k {
+		n := keysize(k[i].Kind)
+		if n == 0 {
+			panic("Composite: unsupported key kind " + k[i].Kind.String())
+		}
+		if k[i].Offset+n > size {
+			panic("Composite: key out of element bounds")
+		}
+	}
+
+	fun := func(a, b unsafe.Pointer) int {
+		for i := range k {
+			if r := k[i].compare(a, b); r != 0 {
+				return r
+			}
+		}
+		return 0
+	}
+	*(*func(unsafe.Pointer, unsafe.Pointer) int)(unsafe.Pointer(v.Pointer())) = fun
+}
+
+func keysize(kind reflect.Kind) uintptr {
+	switch kind {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return 1
+	case reflect.Int16, reflect.Uint16:
+		return 2
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 4
+	case reflect.Int64, reflect.Uint64, reflect.Float64:
+		return 8
+	case reflect.Int, reflect.Uint, reflect.Uintptr:
+		return unsafe.Sizeof(uintptr(0))
+	case reflect.String:
+		return unsafe.Sizeof("")
+	}
+	return 0
+}
+
+func (k *Key) compare(a, b unsafe.Pointer) (r int) {
+	a = unsafe.Add(a, k.Offset)
+	b = unsafe.Add(b, k.Offset)
+
+	switch k.Kind {
+	case reflect.Bool:
+		x, y := *(*bool)(a), *(*bool)(b)
+		if x != y {
+			r = 1
+			if y {
+				r = -1
+			}
+		}
+	case reflect.Int8:
+		r = cmp.Compare(*(*int8)(a), *(*int8)(b))
+	case reflect.Int16:
+		r = cmp.Compare(*(*int16)(a), *(*int16)(b))
+	case reflect.Int32:
+		r = cmp.Compare(*(*int32)(a), *(*int32)(b))
+	case reflect.Int64:
+		r = cmp.Compare(*(*int64)(a), *(*int64)(b))
+	case reflect.Int:
+		r = cmp.Compare(*(*int)(a), *(*int)(b))
+	case reflect.Uint8:
+		r = cmp.Compare(*(*uint8)(a), *(*uint8)(b))
+	case reflect.Uint16:
+		r = cmp.Compare(*(*uint16)(a), *(*uint16)(b))
+	case reflect.Uint32:
+		r = cmp.Compare(*(*uint32)(a), *(*uint32)(b))
+	case reflect.Uint64:
+		r = cmp.Compare(*(*uint64)(a), *(*uint64)(b))
+	case reflect.Uint:
+		r = cmp.Compare(*(*uint)(a), *(*uint)(b))
+	case reflect.Uintptr:
+		r = cmp.Compare(*(*uintptr)(a), *(*uintptr)(b))
+	case reflect.Float32:
+		r = cmp.Compare(*(*float32)(a), *(*float32)(b))
+	case reflect.Float64:
+		r = cmp.Compare(*(*float64)(a), *(*float64)(b))
+	case reflect.String:
+		r = cmp.Compare(*(*string)(a), *(*string)(b))
+	}
+	if k.Desc {
+		r = -r
+	}
+	return r
+}
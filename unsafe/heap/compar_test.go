@@ -0,0 +1,82 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"unsafe"
+)
+
+type job struct {
+	Prio uint16
+	Seq  uint32
+	Cost float32
+}
+
+func TestComposite(t *testing.T) {
+	var compar func(*job, *job) int
+	Composite(&compar,
+		Key{unsafe.Offsetof(job{}.Prio), reflect.Uint16, true},
+		Key{unsafe.Offsetof(job{}.Cost), reflect.Float32, false},
+		Key{unsafe.Offsetof(job{}.Seq), reflect.Uint32, false})
+
+	h := []job{}
+	for i := uint32(0); i < 200; i++ {
+		j := job{uint16(rand.Intn(4)), i, float32(rand.Intn(8))}
+		Push(compar, &h, &j)
+	}
+
+	want := make([]job, len(h))
+	copy(want, h)
+	sort.Slice(want, func(a, b int) bool {
+		return compar(&want[a], &want[b]) < 0
+	})
+
+	for i := 0; len(h) > 0; i++ {
+		x := h[0]
+		Remove(compar, &h, 0)
+		if x != want[i] {
+			t.Fatalf("%d.th pop got %v; want %v", i, x, want[i])
+		}
+	}
+}
+
+type bytes3 struct {
+	A int8
+	B bool
+	C uint8
+}
+
+func TestComposite8(t *testing.T) {
+	var compar func(*bytes3, *bytes3) int
+	Composite(&compar,
+		Key{unsafe.Offsetof(bytes3{}.B), reflect.Bool, false},
+		Key{unsafe.Offsetof(bytes3{}.A), reflect.Int8, false})
+
+	h := []bytes3{{3, true, 0}, {-2, true, 1}, {7, false, 2}, {-9, false, 3}, {0, true, 4}}
+	Heapify(compar, h, h)
+
+	var got []uint8
+	for len(h) > 0 {
+		got = append(got, h[0].C)
+		Remove(compar, &h, 0)
+	}
+	if !reflect.DeepEqual(got, []uint8{3, 2, 1, 4, 0}) {
+		t.Errorf("Has %v", got)
+	}
+}
+
+func TestCompositeBounds(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("key out of element bounds accepted")
+		}
+	}()
+	var compar func(*uint32, *uint32) int
+	Composite(&compar, Key{2, reflect.Uint32, false})
+}
@@ -0,0 +1,259 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// The encoded heap is a header followed by the elements. The elements are
+// stored as the words of the width backend, every word is little-endian.
+//
+//	magic   [4]byte "GMHP"
+//	version uint8   1
+//	width   uint8   8, 32 or 64
+//	flags   uint8   1 if the elements are heap-ordered
+//	_       uint8
+//	size    uint32  element size in bytes
+//	count   uint64  number of the elements
+const (
+	magic      = "GMHP"
+	version    = 1
+	HeaderSize = 20
+)
+
+const maxInt = int(^uint(0) >> 1)
+
+// maxChunk bounds the bytes decoded at once
+const maxChunk = 1 << 20
+
+// ErrFormat is returned when decoding data that is not an encoded heap.
+var ErrFormat = errors.New("heap: invalid encoding")
+
+// errPointers is returned for the element types that hold pointers, their
+// bytes are not meaningful outside of the process.
+var errPointers = errors.New("heap: encoding an element type with pointers")
+
+// Header is the header of an encoded heap.
+type Header struct {
+	Width   uint8  // width backend: 8, 32 or 64
+	Ordered bool   // the elements are heap-ordered
+	Size    uint32 // element size in bytes
+	Count   uint64 // number of the elements
+}
+
+// Width returns the width backend used for the element size.
+func Width(size uintptr) uint8 {
+	if (size & 7) == 0 {
+		return 64
+	}
+	if (size & 3) == 0 {
+		return 32
+	}
+	return 8
+}
+
+// Plain reports whether the values of the type hold no pointers, so that
+// they can be stored as raw bytes.
+func Plain(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32,
+		reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return Plain(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !Plain(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// WriteHeader writes the header of an encoded heap.
+func WriteHeader(w io.Writer, h *Header) error {
+	var b [HeaderSize]byte
+	copy(b[:], magic)
+	b[4] = version
+	b[5] = h.Width
+	if h.Ordered {
+		b[6] = 1
+	}
+	binary.LittleEndian.PutUint32(b[8:], h.Size)
+	binary.LittleEndian.PutUint64(b[12:], h.Count)
+	_, err := w.Write(b[:])
+	return err
+}
+
+// ReadHeader reads the header of an encoded heap.
+func ReadHeader(r io.Reader) (h Header, err error) {
+	var b [HeaderSize]byte
+	if _, err = io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return h, err
+	}
+	if string(b[:4]) != magic || b[4] != version || b[6] > 1 {
+		return h, ErrFormat
+	}
+	h.Width = b[5]
+	h.Ordered = b[6] == 1
+	h.Size = binary.LittleEndian.Uint32(b[8:])
+	h.Count = binary.LittleEndian.Uint64(b[12:])
+	if h.Size == 0 || h.Width != Width(uintptr(h.Size)) {
+		return h, ErrFormat
+	}
+	return h, nil
+}
+
+// Encode writes the heap to w.
+// The heap is a slice, the element type must not hold pointers.
+// The ordered tells if the heap is heapified, Decode then skips Heapify.
+func Encode(w io.Writer, heap interface{}, ordered bool) error {
+	if !Plain(reflect.TypeOf(heap).Elem()) {
+		return errPointers
+	}
+	size := elemsize(heap) //8,4,1
+	n := reflect.ValueOf(heap).Len()
+
+	h := Header{Width(size), ordered, uint32(size), uint64(n)}
+	if err := WriteHeader(w, &h); err != nil {
+		return err
+	}
+	return write(w, heap, size)
+}
+
+// Decode reads a heap encoded by Encode. The elements replace the contents
+// of the heap, the heap is heapified unless the encoding is heap-ordered.
+// The compar is a compare function, it may be nil for a heap-ordered encoding.
+// The heap is a pointer to a slice of the encoded element type, the element
+// type must not hold pointers.
+func Decode(r io.Reader, compar interface{}, heap interface{}) error {
+	return decode(r, compar, heap, Heapify)
+}
+
+// decode is Decode with the heapify of an unordered encoding.
+func decode(r io.Reader, compar interface{}, heap interface{}, heapify func(interface{}, interface{}, interface{})) error {
+	if !Plain(reflect.TypeOf(heap).Elem().Elem()) {
+		return errPointers
+	}
+	h, err := ReadHeader(r)
+	if err != nil {
+		return err
+	}
+	size := elemsize2(heap) //8,4,1
+	if uintptr(h.Size) != size {
+		return fmt.Errorf("heap: element size %d, want %d", h.Size, size)
+	}
+	if h.Count > uint64(maxInt)/uint64(size) {
+		return ErrFormat
+	}
+	if !h.Ordered && compar == nil {
+		return errors.New("heap: decoding an unordered heap without compar")
+	}
+
+	// the count is not trusted, the slice grows with the data actually read
+	n := int(h.Count)
+	chunk := maxChunk / int(size)
+	if chunk == 0 {
+		chunk = 1
+	}
+	v := reflect.ValueOf(heap).Elem()
+	v.Set(reflect.MakeSlice(v.Type(), 0, min(n, chunk)))
+	for i := 0; i < n; i += chunk {
+		m := min(n-i, chunk)
+		v.Grow(m)
+		v.SetLen(i + m)
+		if err := read(r, v.Slice(i, i+m).Interface(), size); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = ErrFormat // a truncated payload
+			}
+			return err
+		}
+	}
+	if !h.Ordered && h.Count > 0 {
+		heapify(compar, v.Interface(), v.Interface())
+	}
+	return nil
+}
+
+// MarshalBinary encodes the heap, the encoding is heap-ordered.
+func (h *Heap) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	err := Encode(&b, reflect.ValueOf(h.Slice).Elem().Interface(), true)
+	return b.Bytes(), err
+}
+
+// UnmarshalBinary decodes the heap into the Slice, see Heap.BottomUp.
+func (h *Heap) UnmarshalBinary(data []byte) error {
+	heapify := Heapify
+	if h.BottomUp {
+		heapify = HeapifyBottomUp
+	}
+	return decode(bytes.NewReader(data), h.Compar, h.Slice, heapify)
+}
+
+// MarshalBinary encodes the heap in the fill order. The tree of the Paged
+// layout is not the binary one, so the encoding is not heap-ordered and the
+// Decode heapifies it.
+func (h *PagedHeap) MarshalBinary() ([]byte, error) {
+	v := reflect.ValueOf(h.Slice).Elem()
+	size := elemsize(v.Interface())
+	s := reflect.MakeSlice(v.Type(), h.n, h.n)
+	src, dst := u8(v.Interface(), size), u8(s.Interface(), size)
+	for i := uintptr(0); i < uintptr(h.n); i++ {
+		j := uintptr(h.Index(int(i)))
+		copy(dst[i*size:i*size+size], src[j*size:])
+	}
+	var b bytes.Buffer
+	err := Encode(&b, s.Interface(), false)
+	return b.Bytes(), err
+}
+
+// UnmarshalBinary decodes the heap into the Slice in the Paged layout.
+func (h *PagedHeap) UnmarshalBinary(data []byte) error {
+	if err := Decode(bytes.NewReader(data), h.Compar, h.Slice); err != nil {
+		return err
+	}
+	*h = *New(h.Compar, h.Slice, Paged).(*PagedHeap)
+	return nil
+}
+
+func write(w io.Writer, heap interface{}, size uintptr) error {
+	if (size & 7) == 0 { // use 8 (64bit)
+		return binary.Write(w, binary.LittleEndian, u64(heap, size/8))
+	}
+	if (size & 3) == 0 { // use 4 (32bit)
+		return binary.Write(w, binary.LittleEndian, u32(heap, size/4))
+	}
+	// use 1 (8bit)
+	_, err := w.Write(u8(heap, size))
+	return err
+}
+
+func read(r io.Reader, heap interface{}, size uintptr) (err error) {
+	if (size & 7) == 0 { // use 8 (64bit)
+		err = binary.Read(r, binary.LittleEndian, u64(heap, size/8))
+	} else if (size & 3) == 0 { // use 4 (32bit)
+		err = binary.Read(r, binary.LittleEndian, u32(heap, size/4))
+	} else { // use 1 (8bit)
+		_, err = io.ReadFull(r, u8(heap, size))
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	heap32 "github.com/gomacro/heap/int32/heap"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	h := []uint32{}
+	for i := uint32(100); i > 0; i-- {
+		Push(Uint32, &h, &i)
+	}
+
+	var b bytes.Buffer
+	if err := Encode(&b, h, true); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != HeaderSize+4*len(h) {
+		t.Errorf("encoded %d bytes; want %d", b.Len(), HeaderSize+4*len(h))
+	}
+
+	var g []uint32
+	if err := Decode(bytes.NewReader(b.Bytes()), nil, &g); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, h) {
+		t.Errorf("Has %v", g)
+	}
+}
+
+func TestEncodeUnordered(t *testing.T) {
+	h := []uint64{5, 3, 8, 1, 9, 0, 2}
+
+	var b bytes.Buffer
+	if err := Encode(&b, h, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := Decode(bytes.NewReader(b.Bytes()), nil, &h); err == nil {
+		t.Errorf("unordered heap decoded without compar")
+	}
+	if err := Decode(bytes.NewReader(b.Bytes()), Uint64, &h); err != nil {
+		t.Fatal(err)
+	}
+	if !ordered(h, Uint64) || len(h) != 7 {
+		t.Errorf("Has %v", h)
+	}
+}
+
+func TestEncodeWidths(t *testing.T) {
+	h := [][3]byte{{0, 9, 9}, {1, 2, 3}, {3, 1, 1}}
+
+	var b bytes.Buffer
+	if err := Encode(&b, h, true); err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := ReadHeader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr != (Header{8, true, 3, 3}) {
+		t.Errorf("Has %v", hdr)
+	}
+
+	var g [][3]byte
+	if err := Decode(bytes.NewReader(b.Bytes()), Rgb, &g); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, h) {
+		t.Errorf("Has %v", g)
+	}
+
+	var wrong []uint32
+	if err := Decode(bytes.NewReader(b.Bytes()), Uint32, &wrong); err == nil {
+		t.Errorf("element size mismatch decoded")
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	var b bytes.Buffer
+	Encode(&b, []uint32{1, 2, 3}, true)
+	data := b.Bytes()
+
+	var h []uint32
+	for n := 0; n < len(data); n++ {
+		err := Decode(bytes.NewReader(data[:n]), Uint32, &h)
+		want := ErrFormat // a truncated payload
+		if n < HeaderSize {
+			want = io.ErrUnexpectedEOF
+		}
+		if err != want {
+			t.Errorf("Decode of %d bytes: %v", n, err)
+		}
+	}
+	huge := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint64(huge[12:], 1<<44)
+	if err := Decode(bytes.NewReader(huge), Uint32, &h); err != ErrFormat {
+		t.Errorf("Decode of a huge count: %v", err)
+	}
+
+	bad := append([]byte("XMHP"), data[4:]...)
+	if err := Decode(bytes.NewReader(bad), Uint32, &h); err != ErrFormat {
+		t.Errorf("Decode of a bad magic: %v", err)
+	}
+}
+
+func TestEncodePointers(t *testing.T) {
+	var b bytes.Buffer
+	if err := Encode(&b, []string{"a", "b"}, true); err != errPointers || b.Len() != 0 {
+		t.Errorf("Encode of strings: %v, %d bytes", err, b.Len())
+	}
+
+	// the bytes of a string header
+	Encode(&b, [][2]uint64{{1, 1}}, true)
+	var h []string
+	if err := Decode(&b, String, &h); err != errPointers || h != nil {
+		t.Errorf("Decode of strings: %v, %q", err, h)
+	}
+}
+
+func TestEncodeInt32(t *testing.T) {
+	h := []int32{3, 1, 2}
+
+	var b bytes.Buffer
+	if err := heap32.Encode(&b, h, false); err != nil {
+		t.Fatal(err)
+	}
+	var g []uint32
+	if err := Decode(&b, Uint32, &g); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, []uint32{1, 3, 2}) {
+		t.Errorf("Has %v", g)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	h := []uint32{1, 2, 3, 4}
+	var m encoding.BinaryMarshaler = &Heap{Compar: Uint32, Slice: &h}
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var g []uint32
+	var u encoding.BinaryUnmarshaler = &Heap{Compar: Uint32, Slice: &g}
+	if err := u.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, h) {
+		t.Errorf("Has %v", g)
+	}
+}
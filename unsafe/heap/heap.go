@@ -41,6 +41,12 @@ func arg64(fun interface{}) (dst func(*uint64, *uint64) int) {
 	mvetype(&fun, &ction)
 	return fun.(func(*uint64, *uint64) int)
 }
+func argp(fun interface{}) (dst func(unsafe.Pointer, unsafe.Pointer) int) {
+	var ction interface{}
+	ction = dst
+	mvetype(&fun, &ction)
+	return fun.(func(unsafe.Pointer, unsafe.Pointer) int)
+}
 func u8(slice interface{}, size uintptr) (src []uint8) {
 	var dst interface{}
 	dst = src
@@ -321,3 +327,30 @@ func Another(compar interface{}, heap interface{}) {
 	return
 
 }
+
+// Prefix loads the k top values to heap[0..k-1] in the sorted order.
+// The heap stays heapified, a sorted prefix of a heap is heap-ordered.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// The complexity is O(k*k + k*log(n)) where n = h.Len().
+func Prefix(compar interface{}, heap interface{}, k int) {
+	size := elemsize(heap) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		heap64.Prefix(&m, arg64(compar), u64(heap, m[0]), k)
+		return
+	}
+
+	if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		heap32.Prefix(&m, arg32(compar), u32(heap, m[0]), k)
+		return
+	}
+
+	// use 1 (8bit)
+	var m = [1]uintptr{size}
+	heap8.Prefix(&m, arg8(compar), u8(heap, m[0]), k)
+	return
+
+}
@@ -6,6 +6,7 @@ package heap
 
 import (
 	"math/rand"
+	"sort"
 	"testing"
 )
 
@@ -215,6 +216,29 @@ func BenchmarkDup(b *testing.B) {
 	}
 }
 
+// packed is a key with an id packed into one 64-bit record
+type packed struct {
+	Key int32
+	ID  uint32
+}
+
+func Packed(a, b *packed) int {
+	return int(a.Key) - int(b.Key)
+}
+
+// compare with BenchmarkKV of the int32 heap
+func BenchmarkPacked(b *testing.B) {
+	var h []packed
+	for i := 0; i < 1e5; i++ {
+		Push(Packed, &h, &packed{rand.Int31(), uint32(i)})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Push(Packed, &h, &packed{rand.Int31(), uint32(i)})
+		Remove(Packed, &h, 0)
+	}
+}
+
 func TestFix(t *testing.T) {
 	h := []uint32{}
 	myHeap(h).verify(t, 0)
@@ -263,3 +287,51 @@ func TestAnother0(t *testing.T) {
 	myHeap(h).verify(t, 0)
 
 }
+
+// down is given the length of the heap, not the last index: the element at
+// the last index must take part in the sift.
+func TestAnother1(t *testing.T) {
+	h := []uint32{0, 5, 3, 6, 7, 4}
+	myHeap(h).verify(t, 0)
+
+	Another(Uint32, h)
+
+	if h[1] != 3 || h[5] != 5 {
+		t.Errorf("Has %v", h)
+	}
+	myHeap(h).verify(t, 0)
+}
+
+func TestPrefix(t *testing.T) {
+	for _, k := range []int{0, 1, 2, 3, 10, 99, 100, 200} {
+		h := []uint32{}
+		for i := 0; i < 100; i++ {
+			x := uint32(rand.Intn(50))
+			Push(Uint32, &h, &x)
+		}
+		want := append([]uint32(nil), h...)
+		sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+
+		Prefix(Uint32, h, k)
+		myHeap(h).verify(t, 0)
+		for i := 0; i < k && i < len(h); i++ {
+			if h[i] != want[i] {
+				t.Fatalf("Prefix(%d): h[%d] = %d; want %d", k, i, h[i], want[i])
+			}
+		}
+	}
+}
+
+func TestPrefixWidths(t *testing.T) {
+	h := [][3]byte{}
+	PushAll(Rgb, &h, [][3]byte{{3, 1, 2}, {1, 2, 3}, {3, 1, 1}, {2, 2, 2}, {0, 9, 9}, {1, 1, 1}})
+	Another(Rgb, h)
+	if h[1] != [3]byte{1, 1, 1} {
+		t.Errorf("Has %v", h)
+	}
+
+	Prefix(Rgb, h, 4)
+	if !ordered(h, Rgb) || h[2] != [3]byte{1, 2, 3} || h[3] != [3]byte{2, 2, 2} {
+		t.Errorf("Has %v", h)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestIndirect(t *testing.T) {
+	data := make([]record256, 300)
+	for i := range data {
+		data[i][0] = uint64(rand.Intn(100))
+		data[i][31] = uint64(i)
+	}
+	h := NewIndirect(Record256, &data, true)
+	for d := range data {
+		h.Push(d)
+	}
+
+	for i := 0; i < 300; i++ {
+		d := rand.Intn(len(data))
+		if p := h.Position(d); p >= 0 {
+			if i&1 == 0 {
+				data[d][0] = uint64(rand.Intn(100))
+				h.Fix(p)
+			} else {
+				h.Remove(p)
+			}
+		} else {
+			h.Push(d)
+		}
+		for j := 1; j < h.Len(); j++ {
+			if Record256(&data[h.Slice[j]], &data[h.Slice[(j-1)/2]]) < 0 {
+				t.Fatalf("heap invariant invalidated at %d", j)
+			}
+		}
+		for j, d := range h.Slice {
+			if h.Position(d) != j {
+				t.Fatalf("position of %d is %d, want %d", d, h.Position(d), j)
+			}
+		}
+	}
+
+	// the data is reallocated by the caller, the heap follows
+	data = append(data, make([]record256, 1000)...)
+	h.Push(len(data) - 1)
+	if h.Position(len(data)-1) < 0 || data[h.Top()][0] != 0 {
+		t.Errorf("top is %v", data[h.Top()][0])
+	}
+
+	last := uint64(0)
+	for h.Len() > 0 {
+		d := h.Top()
+		if data[d][0] < last {
+			t.Fatalf("%d popped after %d", data[d][0], last)
+		}
+		if d < 300 && data[d][31] != uint64(d) {
+			t.Fatalf("data %d moved", d)
+		}
+		last = data[d][0]
+		h.Remove(0)
+	}
+}
+
+func TestIndirectRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("index out of the data accepted")
+		}
+	}()
+	data := make([]uint32, 3)
+	NewIndirect(Uint32, &data, false).Push(3)
+}
+
+func TestIndirectTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("index pushed twice")
+		}
+	}()
+	data := make([]uint32, 3)
+	h := NewIndirect(Uint32, &data, true)
+	h.Push(1)
+	h.Push(1)
+}
+
+func TestIndirectHeapifyRange(t *testing.T) {
+	for _, d := range []int{-1, 3} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("index %d heapified", d)
+				}
+			}()
+			data := make([]uint32, 3)
+			h := NewIndirect(Uint32, &data, true)
+			h.Slice = []int{0, d}
+			h.Heapify()
+		}()
+	}
+
+	// the data grew after the Pos was allocated
+	data := []uint32{3, 2}
+	h := NewIndirect(Uint32, &data, true)
+	data = append(data, 1)
+	h.Slice = []int{0, 1, 2}
+	h.Heapify()
+	if data[h.Top()] != 1 || h.Position(2) != 0 {
+		t.Errorf("top is %d at %d", data[h.Top()], h.Position(2))
+	}
+	if h.Position(-1) != -1 || h.Position(3) != -1 {
+		t.Errorf("positions of the indices out of range: %d, %d", h.Position(-1), h.Position(3))
+	}
+}
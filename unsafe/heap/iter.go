@@ -0,0 +1,60 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	heap64 "github.com/gomacro/heap/64/heap"
+	"iter"
+	"unsafe"
+)
+
+// Ordered returns an iterator over the elements of the heap in the priority
+// order. The heap is not modified, it must not be modified while iterating.
+// The compar is a compare function.
+// The heap is a heapified slice.
+// The complexity is O(k*log(k)) for the first k elements.
+func Ordered[T any](compar interface{}, heap []T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if len(heap) == 0 {
+			return
+		}
+		// the frontier holds the indices of the next candidates
+		frontier := []uint64{0}
+		m := [1]uintptr{1}
+		elem := []uint64{0}
+		cmp := argp(compar)
+		indirect := func(a, b *uint64) int {
+			return cmp(unsafe.Pointer(&heap[*a]), unsafe.Pointer(&heap[*b]))
+		}
+		for len(frontier) > 0 {
+			i := int(frontier[0])
+			heap64.Remove(&m, indirect, &frontier, 0)
+			if !yield(heap[i]) {
+				return
+			}
+			for j := 2*i + 1; j <= 2*i+2 && j < len(heap); j++ {
+				elem[0] = uint64(j)
+				heap64.Push(&m, indirect, &frontier, elem)
+			}
+		}
+	}
+}
+
+// Drain returns an iterator that removes the top element of the heap and
+// yields it until the heap is empty or the iteration stops.
+// The compar is a compare function.
+// The heap is a pointer to a heapified slice.
+// The complexity is O(log(n)) per element where n = h.Len().
+func Drain[T any](compar interface{}, heap *[]T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for len(*heap) > 0 {
+			x := (*heap)[0]
+			Remove(compar, heap, 0)
+			if !yield(x) {
+				return
+			}
+		}
+	}
+}
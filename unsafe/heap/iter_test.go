@@ -0,0 +1,82 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestOrdered(t *testing.T) {
+	h := []uint32{}
+	for i := 0; i < 100; i++ {
+		x := uint32(rand.Intn(50))
+		Push(Uint32, &h, &x)
+	}
+	saved := append([]uint32(nil), h...)
+
+	var got []uint32
+	for x := range Ordered(Uint32, h) {
+		got = append(got, x)
+	}
+	for i := range h {
+		if h[i] != saved[i] {
+			t.Fatalf("the heap was modified")
+		}
+	}
+
+	for i := 0; len(h) > 0; i++ {
+		if got[i] != h[0] {
+			t.Fatalf("%d.th got %d; want %d", i, got[i], h[0])
+		}
+		Remove(Uint32, &h, 0)
+	}
+
+	n := 0
+	for range Ordered(Uint32, saved) {
+		if n++; n == 5 {
+			break
+		}
+	}
+	for range Ordered[uint32](Uint32, nil) {
+		t.Errorf("empty heap yields")
+	}
+}
+
+func TestDrain(t *testing.T) {
+	h := []uint32{}
+	for i := uint32(10); i > 0; i-- {
+		Push(Uint32, &h, &i)
+	}
+
+	want := uint32(1)
+	for x := range Drain(Uint32, &h) {
+		if x != want {
+			t.Errorf("got %d; want %d", x, want)
+		}
+		myHeap(h).verify(t, 0)
+		if want++; want == 6 {
+			break
+		}
+	}
+	if len(h) != 5 || h[0] != 6 {
+		t.Errorf("Has %v", h)
+	}
+}
+
+func TestOrderedWidths(t *testing.T) {
+	h := [][3]byte{}
+	PushAll(Rgb, &h, [][3]byte{{3, 1, 2}, {1, 2, 3}, {3, 1, 1}, {2, 2, 2}, {0, 9, 9}})
+
+	var got [][3]byte
+	for x := range Ordered(Rgb, h) {
+		got = append(got, x)
+	}
+	for i := 1; i < len(got); i++ {
+		if Rgb(&got[i-1], &got[i]) > 0 {
+			t.Errorf("Has %v", got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	heap32 "github.com/gomacro/heap/32/heap"
+	heap64 "github.com/gomacro/heap/64/heap"
+	heap8 "github.com/gomacro/heap/8/heap"
+	"reflect"
+)
+
+// Layout is an arrangement of the heap tree in a slice.
+type Layout uint8
+
+const (
+	// Binary is the implicit binary heap, the children of the index i are at
+	// 2*i+1 and 2*i+2. A sift touches one cache line per level.
+	Binary Layout = iota
+	// Paged is the B-heap layout, the subtrees of several levels are packed
+	// into pages of at least PageSize bytes. A sift touches one page per
+	// several levels.
+	Paged
+)
+
+// PageSize is the size of a page of the Paged layout in bytes, the same as in
+// the int32 heap and on every host. A page holds a power of two elements, at
+// least 4, rounded up to span at least PageSize bytes.
+const PageSize = 4096
+
+// Queue is a heap in a Layout.
+// The i-th element is at the slot Index(i) of the slice, the top is at
+// Index(0). The parent of the i-th element is the Parent(i)-th element.
+type Queue interface {
+	Len() int
+	Index(i int) int
+	Parent(i int) int
+	Push(elem interface{})
+	Remove(i int)
+	Fix(i int)
+}
+
+// New creates a heap in the layout.
+// The compar is a compare function.
+// The heap is a pointer to a heapified slice, the Binary layout uses it as it
+// is, the Paged layout replaces the slice with a heapified paged copy.
+func New(compar interface{}, heap interface{}, layout Layout) Queue {
+	if layout == Binary {
+		return &Heap{Compar: compar, Slice: heap}
+	}
+	size := elemsize2(heap)
+	page := 4
+	for uintptr(page)*size < uintptr(PageSize) {
+		page *= 2
+	}
+
+	v := reflect.ValueOf(heap).Elem()
+	n := v.Len()
+	s := reflect.MakeSlice(v.Type(), heap8.PagedLen(page, n), heap8.PagedLen(page, n))
+	src, dst := u8(v.Interface(), size), u8(s.Interface(), size)
+	for i := 0; i < n; i++ {
+		j := uintptr(heap8.Paged(page, i))
+		copy(dst[j*size:j*size+size], src[uintptr(i)*size:])
+	}
+	v.Set(s)
+	h := &PagedHeap{Compar: compar, Slice: heap, n: n, page: page}
+	h.heapify()
+	return h
+}
+
+// PagedHeap is a heap in the Paged layout, it is created by New.
+// The Compar is a compare function.
+// The Slice is a pointer to a slice that holds the elements at the slots
+// Index(0..Len()-1), the other slots are holes. The slice shall not be resized.
+type PagedHeap struct {
+	Compar interface{}
+	Slice  interface{}
+	n      int
+	page   int
+}
+
+// Len returns the number of the elements.
+func (h *PagedHeap) Len() int { return h.n }
+
+// Index returns the slot of the i-th element in the fill order.
+func (h *PagedHeap) Index(i int) int { return heap8.Paged(h.page, i) }
+
+// Parent returns the index of the parent of the i-th element, i > 0.
+func (h *PagedHeap) Parent(i int) int { return heap8.PagedParent(h.page, i) }
+
+// Push pushes the element x onto the heap.
+// The elem element is a pointer to an element of the same type.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *PagedHeap) Push(elem interface{}) {
+	size := elemsize2(h.Slice) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		uheap, fheap := su64(h.Slice, m[0])
+
+		heap64.PushPaged(&m, h.page, arg64(h.Compar), &uheap, h.n, pu64(elem, m[0]))
+		fu64(uheap, fheap, m[0])
+	} else if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		uheap, fheap := su32(h.Slice, m[0])
+
+		heap32.PushPaged(&m, h.page, arg32(h.Compar), &uheap, h.n, pu32(elem, m[0]))
+		fu32(uheap, fheap, m[0])
+	} else { // use 1 (8bit)
+		var m = [1]uintptr{size}
+		uheap, fheap := su8(h.Slice, m[0])
+
+		heap8.PushPaged(&m, h.page, arg8(h.Compar), &uheap, h.n, pu8(elem, m[0]))
+		fu8(uheap, fheap, m[0])
+	}
+	h.n++
+}
+
+// Remove removes the i-th element in the fill order from the heap.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *PagedHeap) Remove(i int) {
+	size := elemsize2(h.Slice) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		uheap, fheap := su64(h.Slice, m[0])
+
+		heap64.RemovePaged(&m, h.page, arg64(h.Compar), &uheap, h.n, i)
+		fu64(uheap, fheap, m[0])
+	} else if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		uheap, fheap := su32(h.Slice, m[0])
+
+		heap32.RemovePaged(&m, h.page, arg32(h.Compar), &uheap, h.n, i)
+		fu32(uheap, fheap, m[0])
+	} else { // use 1 (8bit)
+		var m = [1]uintptr{size}
+		uheap, fheap := su8(h.Slice, m[0])
+
+		heap8.RemovePaged(&m, h.page, arg8(h.Compar), &uheap, h.n, i)
+		fu8(uheap, fheap, m[0])
+	}
+	h.n--
+}
+
+// Fix re-establishes the heap ordering after the i-th element in the fill
+// order has changed its value.
+// The complexity is O(log(n)) where n = h.Len().
+func (h *PagedHeap) Fix(i int) {
+	size := elemsize2(h.Slice) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		uheap, _ := su64(h.Slice, m[0])
+		heap64.FixPaged(&m, h.page, arg64(h.Compar), uheap, h.n, i)
+		return
+	}
+	if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		uheap, _ := su32(h.Slice, m[0])
+		heap32.FixPaged(&m, h.page, arg32(h.Compar), uheap, h.n, i)
+		return
+	}
+
+	// use 1 (8bit)
+	var m = [1]uintptr{size}
+	uheap, _ := su8(h.Slice, m[0])
+	heap8.FixPaged(&m, h.page, arg8(h.Compar), uheap, h.n, i)
+}
+
+func (h *PagedHeap) heapify() {
+	size := elemsize2(h.Slice) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		uheap, _ := su64(h.Slice, m[0])
+		heap64.HeapifyPaged(&m, h.page, arg64(h.Compar), uheap, h.n)
+		return
+	}
+	if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		uheap, _ := su32(h.Slice, m[0])
+		heap32.HeapifyPaged(&m, h.page, arg32(h.Compar), uheap, h.n)
+		return
+	}
+
+	// use 1 (8bit)
+	var m = [1]uintptr{size}
+	uheap, _ := su8(h.Slice, m[0])
+	heap8.HeapifyPaged(&m, h.page, arg8(h.Compar), uheap, h.n)
+}
@@ -0,0 +1,169 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"fmt"
+	heap32 "github.com/gomacro/heap/int32/heap"
+	"math/rand"
+	"testing"
+)
+
+func Big(a, b *[600]byte) int {
+	return Rgb((*[3]byte)(a[:3]), (*[3]byte)(b[:3]))
+}
+
+func testLayout[T any](t *testing.T, compar func(*T, *T) int, gen func() T) {
+	for _, layout := range []Layout{Binary, Paged} {
+		var h []T
+		q := New(compar, &h, layout)
+		for i := 0; i < 5000; i++ {
+			switch n := q.Len(); {
+			case n > 0 && rand.Intn(3) == 0:
+				q.Remove(rand.Intn(n))
+			case n > 0 && rand.Intn(3) == 0:
+				j := rand.Intn(n)
+				h[q.Index(j)] = gen()
+				q.Fix(j)
+			default:
+				x := gen()
+				q.Push(&x)
+			}
+		}
+		for i := 1; i < q.Len(); i++ {
+			if compar(&h[q.Index(i)], &h[q.Index(q.Parent(i))]) < 0 {
+				t.Fatalf("%T layout %d: heap invariant invalidated at %d", h, layout, i)
+			}
+		}
+
+		var last T
+		for i := 0; q.Len() > 0; i++ {
+			x := h[q.Index(0)]
+			if i > 0 && compar(&x, &last) < 0 {
+				t.Fatalf("%T layout %d: %d.th pop is out of order", h, layout, i)
+			}
+			last = x
+			q.Remove(0)
+		}
+		if len(h) != 0 {
+			t.Errorf("%T layout %d: %d slots left", h, layout, len(h))
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	testLayout(t, Uint32, func() uint32 { return rand.Uint32() })
+	testLayout(t, Uint64, func() uint64 { return rand.Uint64() })
+	testLayout(t, Rgb, func() [3]byte { return [3]byte{byte(rand.Intn(8)), byte(rand.Int()), 0} })
+	testLayout(t, Record13, func() (x record13) {
+		x[0], x[12] = byte(rand.Intn(8)), byte(rand.Int())
+		return x
+	})
+	testLayout(t, Record101, func() (x record101) {
+		x[0], x[100] = byte(rand.Intn(8)), byte(rand.Int())
+		return x
+	})
+	testLayout(t, Big, func() (x [600]byte) {
+		x[0], x[1] = byte(rand.Int()), byte(rand.Int())
+		return x
+	})
+}
+
+func TestPagedMarshalBinary(t *testing.T) {
+	h := []uint64{}
+	for i := 0; i < 5000; i++ {
+		x := rand.Uint64()
+		Push(Uint64, &h, &x)
+	}
+	want := append([]uint64(nil), h...)
+	p := New(Uint64, &h, Paged).(*PagedHeap)
+
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g []uint64
+	if err := (&Heap{Compar: Uint64, Slice: &g}).UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	var s []uint64
+	q := &PagedHeap{Compar: Uint64, Slice: &s}
+	if err := q.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !ordered(g, Uint64) {
+		t.Fatal("the decoded heap is not heap-ordered")
+	}
+	for i := 1; i < q.Len(); i++ {
+		if s[q.Index(i)] < s[q.Index(q.Parent(i))] {
+			t.Fatalf("the decoded paged heap is not heap-ordered at %d", i)
+		}
+	}
+
+	// the same elements
+	for len(want) > 0 {
+		if g[0] != want[0] || s[q.Index(0)] != want[0] {
+			t.Fatalf("%d and %d popped, want %d", g[0], s[q.Index(0)], want[0])
+		}
+		Remove(Uint64, &want, 0)
+		Remove(Uint64, &g, 0)
+		q.Remove(0)
+	}
+}
+
+// the pages span PageSize bytes, the slots are about the elements, not the rows
+// of pages
+func TestPagedSize(t *testing.T) {
+	var h []record13
+	q := New(Record13, &h, Paged).(*PagedHeap)
+	if q.page*13 < PageSize {
+		t.Errorf("a page of %d bytes", q.page*13)
+	}
+	for n := 1; n <= 1<<18; n++ {
+		var x record13
+		x[0] = byte(rand.Int())
+		q.Push(&x)
+		if len(h) > n+2*n/(q.page-2)+q.page || cap(h) > 2*len(h)+q.page {
+			t.Fatalf("%d elements in %d slots of %d", n, len(h), cap(h))
+		}
+	}
+}
+
+// benchmarkLayout measures a remove of the top followed by a push on a heap
+// of len(src) elements.
+func benchmarkLayout(b *testing.B, layout Layout, src []uint64) {
+	h := append([]uint64(nil), src...)
+	q := New(Uint64, &h, layout)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x := rand.Uint64()
+		q.Remove(0)
+		q.Push(&x)
+	}
+}
+
+func BenchmarkLayout(b *testing.B) {
+	for _, n := range []int{1e7, 3e7} {
+		src := make([]uint64, n)
+		for i := range src {
+			src[i] = rand.Uint64()
+		}
+		Heapify(Uint64, src, src)
+		b.Run(fmt.Sprintf("Binary/%d", n), func(b *testing.B) { benchmarkLayout(b, Binary, src) })
+		b.Run(fmt.Sprintf("Paged/%d", n), func(b *testing.B) { benchmarkLayout(b, Paged, src) })
+	}
+}
+
+// TestPagedInt32 lays the 4-byte elements out as the int32 heap does.
+func TestPagedInt32(t *testing.T) {
+	var h []uint32
+	q := New(Uint32, &h, Paged)
+	r := heap32.New(func(a, b *int32) int { return int(*a) - int(*b) }, nil, heap32.Paged)
+	for i := 0; i < 5000; i++ {
+		if q.Index(i) != r.Index(i) {
+			t.Fatalf("Index(%d) = %d; the int32 heap has %d", i, q.Index(i), r.Index(i))
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	heap32 "github.com/gomacro/heap/32/heap"
+	heap64 "github.com/gomacro/heap/64/heap"
+	heap8 "github.com/gomacro/heap/8/heap"
+)
+
+// HeapifyParallel initializes the heap like Heapify, but the subtrees below
+// the top levels are heapified by the workers concurrently, then the top
+// levels are finished serially. It pays off for very large slices.
+// The compar is a compare function, it must be safe for concurrent use.
+// Then heap is a source slice. Dst is a result slice. In place is supported.
+// The workers is the number of goroutines, GOMAXPROCS if it is less than 1.
+// Its complexity is O(n) where n = h.Len().
+func HeapifyParallel(compar interface{}, dst interface{}, heap interface{}, workers int) {
+	size := elemsize(heap) //8,4,1
+
+	if (size & 7) == 0 { // use 8 (64bit)
+		var m = [1]uintptr{size / 8}
+		heap64.HeapifyParallel(&m, arg64(compar), u64(dst, m[0]), u64(heap, m[0]), workers)
+		return
+	}
+	if (size & 3) == 0 { // use 4 (32bit)
+		var m = [1]uintptr{size / 4}
+		heap32.HeapifyParallel(&m, arg32(compar), u32(dst, m[0]), u32(heap, m[0]), workers)
+		return
+	}
+
+	// use 1 (8bit)
+	var m = [1]uintptr{size}
+	heap8.HeapifyParallel(&m, arg8(compar), u8(dst, m[0]), u8(heap, m[0]), workers)
+}
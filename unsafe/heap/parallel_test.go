@@ -0,0 +1,63 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func testHeapifyParallel[T comparable](t *testing.T, compar func(*T, *T) int, gen func() T) {
+	for _, n := range []int{1, 2, 3, 10, 100, 1000, 12345} {
+		for _, workers := range []int{0, 1, 2, 3, 8} {
+			h := make([]T, n)
+			count := make(map[T]int)
+			for i := range h {
+				h[i] = gen()
+				count[h[i]]++
+			}
+			HeapifyParallel(compar, h, h, workers)
+			if !ordered(h, compar) {
+				t.Fatalf("%T: n=%d workers=%d: HeapifyParallel is not heap-ordered", h, n, workers)
+			}
+			for _, x := range h {
+				count[x]--
+			}
+			for x, c := range count {
+				if c != 0 {
+					t.Fatalf("%T: n=%d workers=%d: %v counted %d times off", h, n, workers, x, c)
+				}
+			}
+		}
+	}
+}
+
+func TestHeapifyParallel(t *testing.T) {
+	testHeapifyParallel(t, Uint32, func() uint32 { return uint32(rand.Intn(1000)) })
+	testHeapifyParallel(t, Uint64, func() uint64 { return uint64(rand.Intn(1000)) })
+	testHeapifyParallel(t, Rgb, func() [3]byte { return [3]byte{byte(rand.Intn(16)), byte(rand.Intn(16)), 0} })
+	testHeapifyParallel(t, String, func() string { return strconv.Itoa(rand.Intn(1000)) })
+}
+
+func BenchmarkHeapifyParallel(b *testing.B) {
+	src := make([]uint64, 1e7)
+	for i := range src {
+		src[i] = rand.Uint64()
+	}
+	h := make([]uint64, len(src))
+	b.Run("Serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			copy(h, src)
+			Heapify(Uint64, h, h)
+		}
+	})
+	b.Run("Parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			copy(h, src)
+			HeapifyParallel(Uint64, h, h, 0)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright 2015 The GOMACRO Authors. All rights reserved.
+// Use of this source code is governed by a GPLv2-style
+// license that can be found in the LICENSE file.
+
+package heap
+
+import (
+	"math/rand"
+	"testing"
+)
+
+type (
+	record12  [3]uint32
+	record13  [13]byte
+	record16  [2]uint64
+	record64  [8]uint64
+	record100 [100]byte
+	record101 [101]byte
+	record104 [13]uint64
+	record256 [32]uint64
+	record300 [75]uint32
+	record301 [301]byte
+)
+
+func Record12(a, b *record12) int   { return Uint32(&a[0], &b[0]) }
+func Record13(a, b *record13) int   { return Rgb((*[3]byte)(a[:]), (*[3]byte)(b[:])) }
+func Record16(a, b *record16) int   { return Uint64(&a[0], &b[0]) }
+func Record64(a, b *record64) int   { return Uint64(&a[0], &b[0]) }
+func Record100(a, b *record100) int { return Rgb((*[3]byte)(a[:]), (*[3]byte)(b[:])) }
+func Record101(a, b *record101) int { return Rgb((*[3]byte)(a[:]), (*[3]byte)(b[:])) }
+func Record104(a, b *record104) int { return Uint64(&a[0], &b[0]) }
+func Record256(a, b *record256) int { return Uint64(&a[0], &b[0]) }
+func Record300(a, b *record300) int { return Uint32(&a[0], &b[0]) }
+func Record301(a, b *record301) int { return int(a[0]) - int(b[0]) }
+
+// testSift sifts the records filled with a key in both layouts, the popped
+// records must be ordered and intact.
+func testSift[T any, E comparable](t *testing.T, compar func(*T, *T) int, elems func(*T) []E, key func(uint32) E) {
+	fill := func(x *T) {
+		k := key(rand.Uint32())
+		for i := range elems(x) {
+			elems(x)[i] = k
+		}
+	}
+	for _, layout := range []Layout{Binary, Paged} {
+		var h []T
+		q := New(compar, &h, layout)
+		for i := 0; i < 2000; i++ {
+			switch n := q.Len(); {
+			case n > 0 && rand.Intn(3) == 0:
+				q.Remove(rand.Intn(n))
+			case n > 0 && rand.Intn(3) == 0:
+				j := rand.Intn(n)
+				fill(&h[q.Index(j)])
+				q.Fix(j)
+			default:
+				var x T
+				fill(&x)
+				q.Push(&x)
+			}
+		}
+
+		var last T
+		for i := 0; q.Len() > 0; i++ {
+			x := h[q.Index(0)]
+			for _, e := range elems(&x) {
+				if e != elems(&x)[0] {
+					t.Fatalf("%T layout %d: %d.th pop is torn", h, layout, i)
+				}
+			}
+			if i > 0 && compar(&x, &last) < 0 {
+				t.Fatalf("%T layout %d: %d.th pop is out of order", h, layout, i)
+			}
+			last = x
+			q.Remove(0)
+		}
+	}
+}
+
+// TestSiftChunks moves the records of more than one chunk of the 8-bit and
+// the 32-bit backends.
+func TestSiftChunks(t *testing.T) {
+	testSift(t, Record301, func(x *record301) []byte { return x[:] }, func(k uint32) byte { return byte(k) })
+	testSift(t, Record300, func(x *record300) []uint32 { return x[:] }, func(k uint32) uint32 { return k })
+}
+
+// benchmarkSift measures a push followed by a remove of the top on a heap of
+// 1e5 elements of the type T.
+func benchmarkSift[T any](b *testing.B, compar func(*T, *T) int, set func(*T, uint32)) {
+	h := make([]T, 1e5)
+	for i := range h {
+		set(&h[i], rand.Uint32())
+	}
+	Heapify(compar, h, h)
+	elems := make([]T, 1024)
+	for i := range elems {
+		set(&elems[i], rand.Uint32())
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Push(compar, &h, &elems[i%len(elems)])
+		Remove(compar, &h, 0)
+	}
+}
+
+func BenchmarkSift(b *testing.B) {
+	b.Run("4", func(b *testing.B) {
+		benchmarkSift(b, Uint32, func(x *uint32, k uint32) { *x = k })
+	})
+	b.Run("12", func(b *testing.B) {
+		benchmarkSift(b, Record12, func(x *record12, k uint32) { x[0] = k })
+	})
+	b.Run("13", func(b *testing.B) {
+		benchmarkSift(b, Record13, func(x *record13, k uint32) { x[0], x[1], x[2] = byte(k>>16), byte(k>>8), byte(k) })
+	})
+	b.Run("16", func(b *testing.B) {
+		benchmarkSift(b, Record16, func(x *record16, k uint32) { x[0] = uint64(k) })
+	})
+	b.Run("64", func(b *testing.B) {
+		benchmarkSift(b, Record64, func(x *record64, k uint32) { x[0] = uint64(k) })
+	})
+	b.Run("100", func(b *testing.B) {
+		benchmarkSift(b, Record100, func(x *record100, k uint32) { x[0], x[1], x[2] = byte(k>>16), byte(k>>8), byte(k) })
+	})
+	b.Run("101", func(b *testing.B) {
+		benchmarkSift(b, Record101, func(x *record101, k uint32) { x[0], x[1], x[2] = byte(k>>16), byte(k>>8), byte(k) })
+	})
+	b.Run("104", func(b *testing.B) {
+		benchmarkSift(b, Record104, func(x *record104, k uint32) { x[0] = uint64(k) })
+	})
+	b.Run("256", func(b *testing.B) {
+		benchmarkSift(b, Record256, func(x *record256, k uint32) { x[0] = uint64(k) })
+	})
+}